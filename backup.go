@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"k8s-hw/internal/config"
+	"k8s-hw/internal/db"
+)
+
+// runBackup — подкоманда `app backup`: сохраняет таблицы приложения (db.BackupTables) со
+// версией схемы в gzip-файл в APP_DATA_DIR/backup или в stdout (-o -).
+func runBackup(args []string) error {
+	fs := flag.NewFlagSet("backup", flag.ContinueOnError)
+	out := fs.String("o", "", "output file, - for stdout (default APP_DATA_DIR/backup/backup-<time>.gz)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	cfg, err := loadDBConfig(fs.Args())
+	if err != nil {
+		return err
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	client, err := db.New(ctx, cfg.Postgres)
+	if err != nil {
+		return fmt.Errorf("connect: %w", err)
+	}
+	defer client.Close()
+
+	if *out == "-" {
+		res, err := client.Backup(ctx, os.Stdout)
+		if err == nil {
+			log.Printf("backup written to stdout: schema version %d, %s", res.SchemaVersion, rowCounts(res))
+		}
+		return err
+	}
+	path := *out
+	if path == "" {
+		path = filepath.Join(cfg.DataDir, "backup", "backup-"+time.Now().UTC().Format("20060102T150405Z")+".gz")
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	// пишем во временный файл рядом: неполная копия не должна выглядеть готовой
+	f, err := os.CreateTemp(filepath.Dir(path), ".backup-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	res, err := client.Backup(ctx, f)
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	if err := os.Rename(f.Name(), path); err != nil {
+		return err
+	}
+	log.Printf("backup written to %s: schema version %d, %s", path, res.SchemaVersion, rowCounts(res))
+	return nil
+}
+
+// runRestore — подкоманда `app restore -i FILE`: загружает копию из runBackup в пустую БД
+// с той же версией схемы (см. db.Client.Restore).
+func runRestore(args []string) error {
+	fs := flag.NewFlagSet("restore", flag.ContinueOnError)
+	in := fs.String("i", "", "backup file to restore, - for stdin (required)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *in == "" {
+		return errors.New("-i is required")
+	}
+	cfg, err := loadDBConfig(fs.Args())
+	if err != nil {
+		return err
+	}
+	var r io.Reader = os.Stdin
+	if *in != "-" {
+		f, err := os.Open(*in)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	client, err := db.New(ctx, cfg.Postgres)
+	if err != nil {
+		return fmt.Errorf("connect: %w", err)
+	}
+	defer client.Close()
+	res, err := client.Restore(ctx, r)
+	if err != nil {
+		return err
+	}
+	log.Printf("restored %s: schema version %d, %s", *in, res.SchemaVersion, rowCounts(res))
+	return nil
+}
+
+// loadDBConfig загружает конфигурацию как сервер (флаги после флагов подкоманды, env, каталоги
+// ConfigMap/Secret) и требует настроенный Postgres.
+func loadDBConfig(args []string) (config.Config, error) {
+	base, err := config.LoadArgs(args)
+	if err != nil {
+		return base, err
+	}
+	cfg, err := base.ApplyDirs()
+	if err != nil {
+		return cfg, err
+	}
+	if !cfg.Postgres.Enabled() {
+		return cfg, errors.New("postgres config incomplete (need APP_POSTGRES_DSN or APP_POSTGRES_HOST/USER/DB)")
+	}
+	return cfg, nil
+}
+
+func rowCounts(res db.BackupResult) string {
+	parts := make([]string, 0, len(db.BackupTables))
+	for _, t := range db.BackupTables {
+		if n, ok := res.Rows[t]; ok {
+			parts = append(parts, fmt.Sprintf("%s=%d", t, n))
+		}
+	}
+	return strings.Join(parts, " ")
+}
@@ -6,6 +6,7 @@ import (
 	"log"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"syscall"
 	"time"
 
@@ -21,11 +22,12 @@ func main() {
 		log.Fatalf("load config: %v", err)
 	}
 	pg := cfg.Postgres
-	if pg.Host == "" || pg.User == "" || pg.DB == "" {
-		log.Fatalf("postgres config incomplete (need APP_POSTGRES_HOST/USER/DB)")
+	if !pg.Enabled() {
+		log.Fatalf("postgres config incomplete (need APP_POSTGRES_DSN or APP_POSTGRES_HOST/USER/DB)")
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	// 30s на вставку и ещё до 5 минут на retention
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute+30*time.Second)
 	defer cancel()
 
 	// handle signals
@@ -62,5 +64,67 @@ func main() {
 		log.Fatalf("insert cron run: %v", err)
 	}
 	log.Printf("cron run inserted id=%d executed_at=%s", id, ts.Format(time.RFC3339Nano))
+
+	// до retention: агрегаты /stats переживают удаление исходных записей
+	if n, err := client.RollupRequests(ctx, time.Now(), cfg.RollupLookback()); err != nil {
+		log.Printf("WARN: stats rollup: %v", err) // /stats досчитает хвост по requests
+	} else if n > 0 {
+		log.Printf("stats rollup: %d buckets updated", n)
+	}
+	if err := maintainPartitions(ctx, cfg, client); err != nil {
+		log.Printf("WARN: partitions: %v", err) // retention ниже всё равно удалит старые записи
+	}
+	if err := enforceRetention(ctx, cfg, client); err != nil {
+		client.Close()
+		log.Fatalf("retention: %v", err)
+	}
 	fmt.Println("OK")
 }
+
+// maintainPartitions создаёт будущие партиции requests и удаляет целиком те, что старше
+// retention requests (если таблица партиционирована миграцией 0003).
+func maintainPartitions(ctx context.Context, cfg config.Config, client *db.Client) error {
+	ok, err := client.RequestsPartitioned(ctx)
+	if err != nil || !ok {
+		return err
+	}
+	policies, err := cfg.RetentionPolicies()
+	if err != nil {
+		return err
+	}
+	res, err := client.MaintainPartitions(ctx, time.Now(), cfg.PartitionsAheadMonths, policies["requests"].MaxAge, archiveDir(cfg))
+	if len(res.Created) > 0 || len(res.Dropped) > 0 {
+		log.Printf("partitions: created=%v dropped=%v archives=%v", res.Created, res.Dropped, res.Archives)
+	}
+	return err
+}
+
+func archiveDir(cfg config.Config) string {
+	if !cfg.RetentionArchive {
+		return ""
+	}
+	return filepath.Join(cfg.DataDir, "archive")
+}
+
+// enforceRetention применяет APP_RETENTION_* ко всем таблицам. Пачки удаляются в отдельных
+// транзакциях, поэтому при таймауте оставшееся удалится следующим запуском.
+func enforceRetention(ctx context.Context, cfg config.Config, client *db.Client) error {
+	policies, err := cfg.RetentionPolicies()
+	if err != nil {
+		return err
+	}
+	for _, table := range config.RetentionTables {
+		r, ok := policies[table]
+		if !ok {
+			continue
+		}
+		res, err := client.EnforceRetention(ctx, db.RetentionPolicy{Table: table, MaxAge: r.MaxAge, MaxRows: r.MaxRows}, cfg.RetentionBatchSize, archiveDir(cfg))
+		if res.Deleted > 0 || err != nil {
+			log.Printf("retention %s: deleted=%d archive=%q", table, res.Deleted, res.Archive)
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", table, err)
+		}
+	}
+	return nil
+}
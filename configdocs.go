@@ -0,0 +1,28 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"k8s-hw/internal/config"
+)
+
+// runConfigDocs — подкоманда `app config-docs`: генерирует документацию по конфигурации из config.Config.
+func runConfigDocs(args []string) error {
+	fs := flag.NewFlagSet("config-docs", flag.ContinueOnError)
+	format := fs.String("format", "markdown", "output format: markdown, env, helm-schema")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	switch *format {
+	case "markdown":
+		return config.WriteMarkdown(os.Stdout)
+	case "env":
+		return config.WriteEnvExample(os.Stdout)
+	case "helm-schema":
+		return config.WriteHelmSchema(os.Stdout)
+	default:
+		return fmt.Errorf("unknown format %q (want markdown, env or helm-schema)", *format)
+	}
+}
@@ -6,7 +6,7 @@ toolchain go1.24.5
 
 require (
 	github.com/jackc/pgx/v5 v5.5.5
-	github.com/kelseyhightower/envconfig v1.4.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
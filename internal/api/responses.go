@@ -20,21 +20,48 @@ type envResponse struct {
 	} `json:"body"`
 }
 
+// healthCheck is the result of a single check in ?verbose mode.
+type healthCheck struct {
+	Name      string    `json:"name"`
+	Status    string    `json:"status"`
+	Critical  bool      `json:"critical"`
+	Error     string    `json:"error,omitempty"`
+	LatencyMs float64   `json:"latencyMs"`
+	CheckedAt time.Time `json:"checkedAt"`
+	Cached    bool      `json:"cached,omitempty"`
+}
+
 // swagger:response healthzResponse
 // Health status.
 type healthzResponse struct {
 	// in: body
 	Body struct {
-		Status string `json:"status"`
+		Status string        `json:"status"`
+		Failed []string      `json:"failed,omitempty"`
+		Checks []healthCheck `json:"checks,omitempty"`
 	} `json:"body"`
 }
 
 // swagger:response readinessResponse
-// Readiness status.
+// Readiness status: "true", "degraded" (optional dependencies down) or "false".
 type readinessResponse struct {
 	// in: body
 	Body struct {
-		Ready string `json:"ready"`
+		Ready    string        `json:"ready"`
+		Failed   []string      `json:"failed,omitempty"`
+		Degraded []string      `json:"degraded,omitempty"`
+		Checks   []healthCheck `json:"checks,omitempty"`
+	} `json:"body"`
+}
+
+// swagger:response startupResponse
+// Startup status.
+type startupResponse struct {
+	// in: body
+	Body struct {
+		Started string        `json:"started"`
+		Failed  []string      `json:"failed,omitempty"`
+		Checks  []healthCheck `json:"checks,omitempty"`
 	} `json:"body"`
 }
 
@@ -57,6 +84,54 @@ type secretResponse struct {
 	} `json:"body"`
 }
 
+// swagger:response secretsResponse
+// All keys of the configured secret source, masked per key policy.
+type secretsResponse struct {
+	// in: body
+	Body struct {
+		Source  string `json:"source"`
+		Secrets []struct {
+			Key   string `json:"key"`
+			Mask  string `json:"mask"`
+			Value string `json:"value"`
+		} `json:"secrets"`
+	} `json:"body"`
+}
+
+// swagger:response configResponse
+// Effective configuration (secrets redacted) and the source layer of every key.
+type configResponse struct {
+	// in: body
+	Body struct {
+		Config  map[string]any    `json:"config"`
+		Sources map[string]string `json:"sources"`
+	} `json:"body"`
+}
+
+// swagger:response inFlightResponse
+// Requests currently being processed.
+type inFlightResponse struct {
+	// in: body
+	Body struct {
+		Draining bool `json:"draining"`
+		Count    int  `json:"count"`
+		Requests []struct {
+			Method     string `json:"method"`
+			Path       string `json:"path"`
+			DurationMs int64  `json:"durationMs"`
+		} `json:"requests"`
+	} `json:"body"`
+}
+
+// swagger:response errorResponse
+// Error description.
+type errorResponse struct {
+	// in: body
+	Body struct {
+		Error string `json:"error"`
+	} `json:"body"`
+}
+
 // swagger:response pvcTestResponse
 // PVC test file creation result.
 type pvcTestResponse struct {
@@ -79,14 +154,83 @@ type dbInsertResponse struct {
 	} `json:"body"`
 }
 
+// swagger:response dbQueuedResponse
+// Insert queued for a batched write (APP_REQUESTS_WRITE_MODE=async).
+type dbQueuedResponse struct {
+	// in: body
+	Body struct {
+		Queued    bool      `json:"queued"`
+		CreatedAt time.Time `json:"createdAt"`
+	} `json:"body"`
+}
+
+// swagger:response dbImportResponse
+// Bulk insert result: number of inserted records and their id range.
+type dbImportResponse struct {
+	// in: body
+	Body struct {
+		Count   int64 `json:"count"`
+		FirstID int64 `json:"firstId"`
+		LastID  int64 `json:"lastId"`
+	} `json:"body"`
+}
+
+// swagger:response dbListResponse
+// Latest db records, newest first.
+type dbListResponse struct {
+	// in: body
+	Body struct {
+		Items []struct {
+			ID        int64     `json:"id"`
+			CreatedAt time.Time `json:"createdAt"`
+			Pod       string    `json:"pod,omitempty"`
+			Path      string    `json:"path,omitempty"`
+		} `json:"items"`
+	} `json:"body"`
+}
+
+// swagger:response statsResponse
+// Request counts per time bucket; every bucket of the range is present (zero when empty).
+type statsResponse struct {
+	// in: body
+	Body struct {
+		Bucket string    `json:"bucket"`
+		From   time.Time `json:"from"`
+		To     time.Time `json:"to"`
+		Group  string    `json:"group,omitempty"`
+		Points []struct {
+			Bucket time.Time `json:"bucket"`
+			Key    string    `json:"key,omitempty"`
+			Count  int64     `json:"count"`
+		} `json:"points"`
+	} `json:"body"`
+}
+
+// swagger:response exportResponse
+// Streamed table rows: NDJSON (one JSON object per line) or CSV with a header row.
+type exportResponse struct {
+	// in: body
+	Body string `json:"body"`
+}
+
 // dummy usage to silence linters about unused types (they are used by swagger annotations)
 var _ = []any{
 	(*helloResponse)(nil),
 	(*envResponse)(nil),
 	(*healthzResponse)(nil),
 	(*readinessResponse)(nil),
+	(*startupResponse)(nil),
 	(*versionResponse)(nil),
 	(*secretResponse)(nil),
+	(*secretsResponse)(nil),
+	(*configResponse)(nil),
+	(*inFlightResponse)(nil),
+	(*errorResponse)(nil),
 	(*pvcTestResponse)(nil),
 	(*dbInsertResponse)(nil),
+	(*dbListResponse)(nil),
+	(*dbQueuedResponse)(nil),
+	(*dbImportResponse)(nil),
+	(*statsResponse)(nil),
+	(*exportResponse)(nil),
 }
@@ -1,6 +1,7 @@
 package api
 
 import (
+	"expvar"
 	"net/http"
 
 	"k8s-hw/docs"
@@ -16,12 +17,23 @@ func NewMux(cfg config.Config) *http.ServeMux {
 	mux.HandleFunc("/test-env", handler.TestEnv)
 	mux.HandleFunc("/healthz", handler.Healthz)
 	mux.HandleFunc("/readyz", handler.Readyz)
+	mux.HandleFunc("/startupz", handler.Startupz)
 	mux.HandleFunc("/version", handler.VersionHandler)
 	mux.HandleFunc("/secret", handler.Secret)
-	mux.HandleFunc("/swagger.json", docs.SwaggerJSON)
-	mux.HandleFunc("/swagger", docs.SwaggerUI)
-	mux.HandleFunc("/swagger/", docs.SwaggerUI)
+	mux.HandleFunc("/secrets", handler.Secrets)
+	if cfg.SwaggerEnabled {
+		mux.HandleFunc("/swagger.json", docs.SwaggerJSON)
+		mux.HandleFunc("/swagger", docs.SwaggerUI)
+		mux.HandleFunc("/swagger/", docs.SwaggerUI)
+	}
 	mux.HandleFunc("/pvc-test", handler.PvcTest)
-	mux.HandleFunc("/db/requests", handler.InsertRequest)
+	mux.HandleFunc("/db/requests", handler.RequireDependency("db", handler.Requests))
+	mux.HandleFunc("/db/requests/batch", handler.RequireDependency("db", handler.ImportRequests))
+	mux.HandleFunc("/db/export/requests", handler.RequireDependency("db", handler.ExportRequests))
+	mux.HandleFunc("/db/export/cron_runs", handler.RequireDependency("db", handler.ExportCronRuns))
+	mux.HandleFunc("/stats", handler.RequireDependency("db", handler.Stats))
+	mux.HandleFunc("/admin/config", handler.AdminOnly(handler.EffectiveConfig))
+	mux.HandleFunc("/admin/inflight", handler.AdminOnly(handler.InFlightRequests))
+	mux.HandleFunc("/admin/metrics", handler.AdminOnly(expvar.Handler().ServeHTTP))
 	return mux
 }
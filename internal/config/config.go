@@ -1,50 +1,249 @@
 package config
 
 import (
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
-
-	"github.com/kelseyhightower/envconfig"
 )
 
-// Config описывает параметры запуска сервиса, загружаемые из переменных окружения (префикс APP_).
+// Config описывает параметры запуска сервиса. Значения собираются по слоям (см. LoadArgs):
+// default-теги, файл YAML/JSON, переменные окружения (префикс APP_), флаги командной строки.
+// Имена тегов envconfig сохранены: из них выводятся имена переменных, ключей файла и флагов,
+// а тег desc — описание для сгенерированной документации (см. WriteMarkdown, `app config-docs`).
 //
-// Переменные:
-//   APP_PORT (string)                       - порт HTTP (default 8080)
-//   APP_READINESS_WARMUP_SECONDS (int)      - время (сек) для /readyz warming (default 1)
-//   APP_SHUTDOWN_TIMEOUT_SECONDS (int)      - таймаут graceful shutdown (default 10)
-//   APP_CONFIG_MAP_ENV_VAR (string)         - значение для /test-env (default пусто)
-//   APP_SECRET_USERNAME (string)            - (из k8s Secret) имя пользователя (optional)
-//   APP_SECRET_PASSWORD (string)            - (из k8s Secret) пароль (optional)
-//   APP_DATA_DIR (string)                   - директория для данных / PVC (default /var/lib/k8s-test-backend/data)
-//   APP_POD_NAME (string)                   - имя пода
-
+// Для полей с тегом sensitive:"true" значение можно передать файлом: APP_<NAME>_FILE=/path
+// (например APP_SECRET_PASSWORD_FILE, APP_POSTGRES_PASSWORD_FILE). Одновременно задавать
+// APP_<NAME> и APP_<NAME>_FILE нельзя.
 type Config struct {
-	Port                   string   `envconfig:"PORT" default:"8080"`
-	ReadinessWarmupSeconds int      `envconfig:"READINESS_WARMUP_SECONDS" default:"1"`
-	ShutdownTimeoutSeconds int      `envconfig:"SHUTDOWN_TIMEOUT_SECONDS" default:"10"`
-	ConfigMapEnvVar        string   `envconfig:"CONFIG_MAP_ENV_VAR" default:""`
-	SecretUsername         string   `envconfig:"SECRET_USERNAME" default:""`
-	SecretPassword         string   `envconfig:"SECRET_PASSWORD" default:""`
-	DataDir                string   `envconfig:"DATA_DIR" default:"/var/lib/k8s-test-backend/data"`
-	PodName                string   `envconfig:"POD_NAME" default:""`
-	Postgres               Postgres `envconfig:"POSTGRES"`
+	Profile                 string            `envconfig:"PROFILE" default:"dev" desc:"Профиль окружения: dev, staging, prod (prod включает строгую валидацию)"`
+	Port                    string            `envconfig:"PORT" default:"8080" desc:"Порт HTTP"`
+	ReadinessWarmupSeconds  int               `envconfig:"READINESS_WARMUP_SECONDS" default:"1" desc:"Прогрев /readyz (warming), сек"`
+	ShutdownTimeoutSeconds  int               `envconfig:"SHUTDOWN_TIMEOUT_SECONDS" default:"10" desc:"Таймаут graceful shutdown, сек"`
+	PreDrainDelaySeconds    int               `envconfig:"PRE_DRAIN_DELAY_SECONDS" default:"5" desc:"Пауза между провалом /readyz и закрытием listener при SIGTERM, сек"`
+	ConfigMapEnvVar         string            `envconfig:"CONFIG_MAP_ENV_VAR" default:"" desc:"Значение для /test-env"`
+	SecretUsername          string            `envconfig:"SECRET_USERNAME" default:"" sensitive:"true" desc:"Пользователь (k8s Secret)"`
+	SecretPassword          string            `envconfig:"SECRET_PASSWORD" default:"" sensitive:"true" desc:"Пароль (k8s Secret)"`
+	SecretsDir              string            `envconfig:"SECRETS_DIR" default:"" desc:"Каталог Secret, все ключи которого отдаёт /secrets"`
+	SecretsPrefix           string            `envconfig:"SECRETS_PREFIX" default:"" desc:"Префикс env, отдаваемых /secrets (если каталог не задан)"`
+	SecretMaskDefault       string            `envconfig:"SECRET_MASK_DEFAULT" default:"full" desc:"Маскирование /secrets: full, partial, length, sha256"`
+	SecretMasks             map[string]string `envconfig:"SECRET_MASKS" default:"" desc:"Маскирование по ключам (password:partial,token:sha256)"`
+	DataDir                 string            `envconfig:"DATA_DIR" default:"/var/lib/k8s-test-backend/data" desc:"Каталог для данных / PVC"`
+	PodName                 string            `envconfig:"POD_NAME" default:"" desc:"Имя пода (Downward API)"`
+	SwaggerEnabled          bool              `envconfig:"SWAGGER_ENABLED" default:"true" desc:"Отдавать /swagger и /swagger.json"`
+	AdminToken              string            `envconfig:"ADMIN_TOKEN" default:"" sensitive:"true" desc:"Bearer-токен для /admin/* (пусто — без авторизации)"`
+	WatchDirs               []string          `envconfig:"WATCH_DIRS" default:"" desc:"Каталоги смонтированных ConfigMap/Secret для hot reload"`
+	ReloadIntervalSeconds   int               `envconfig:"RELOAD_INTERVAL_SECONDS" default:"5" desc:"Период опроса APP_WATCH_DIRS, сек"`
+	HealthIntervalSeconds   int               `envconfig:"HEALTH_INTERVAL_SECONDS" default:"5" desc:"Период фоновых проверок зависимостей (БД, Vault, диск), сек"`
+	HealthFailureThreshold  int               `envconfig:"HEALTH_FAILURE_THRESHOLD" default:"3" desc:"Сколько провалов подряд переводят зависимость в not ready"`
+	HealthSuccessThreshold  int               `envconfig:"HEALTH_SUCCESS_THRESHOLD" default:"1" desc:"Сколько успехов подряд возвращают зависимость в ready"`
+	DependencyPolicy        map[string]string `envconfig:"DEPENDENCY_POLICY" default:"db:required" desc:"Политика зависимостей для /readyz: name:required|optional (db, storage, disk, vault, cron); optional-провал даёт degraded"`
+	VaultAddr               string            `envconfig:"VAULT_ADDR" default:"" desc:"Адрес Vault для проверки /readyz (пусто — без проверки)"`
+	CronStaleAfterSeconds   int               `envconfig:"CRON_STALE_AFTER_SECONDS" default:"180" desc:"Через сколько секунд без записей в cron_runs CronJob считается зависшим (0 — не проверять)"`
+	DiskMinFreeMB           int               `envconfig:"DISK_MIN_FREE_MB" default:"50" desc:"Минимум свободного места в APP_DATA_DIR, МиБ (0 — не проверять)"`
+	RequestsWriteMode       string            `envconfig:"REQUESTS_WRITE_MODE" default:"sync" desc:"Запись POST /db/requests: sync (INSERT в запросе, ответ с id) или async (очередь и пачки, ответ 202)"`
+	RequestsBatchSize       int               `envconfig:"REQUESTS_BATCH_SIZE" default:"500" desc:"Режим async: размер пачки, при котором очередь сбрасывается сразу"`
+	RequestsBatchIntervalMs int               `envconfig:"REQUESTS_BATCH_INTERVAL_MS" default:"100" desc:"Режим async: период сброса неполной пачки, мс"`
+	RequestsQueueSize       int               `envconfig:"REQUESTS_QUEUE_SIZE" default:"10000" desc:"Режим async: ёмкость очереди; при переполнении POST отвечает 503"`
+	RetentionMaxAge         map[string]string `envconfig:"RETENTION_MAX_AGE" default:"" desc:"Максимальный возраст записей по таблицам (requests, cron_runs): requests:30d,cron_runs:168h"`
+	RetentionMaxRows        map[string]string `envconfig:"RETENTION_MAX_ROWS" default:"" desc:"Максимум хранимых записей по таблицам: requests:1000000"`
+	PartitionsAheadMonths   int               `envconfig:"PARTITIONS_AHEAD_MONTHS" default:"3" desc:"На сколько месяцев вперёд CronJob создаёт партиции requests"`
+	RetentionBatchSize      int               `envconfig:"RETENTION_BATCH_SIZE" default:"1000" desc:"Сколько записей удаляет одна транзакция retention"`
+	RetentionArchive        bool              `envconfig:"RETENTION_ARCHIVE" default:"false" desc:"Перед удалением сохранять записи в APP_DATA_DIR/archive/<table>-<время>.ndjson.gz"`
+	RollupLookbackMinutes   int               `envconfig:"ROLLUP_LOOKBACK_MINUTES" default:"5" desc:"Сколько последних минут CronJob пересчитывает в requests_rollup, чтобы учесть запоздавшие записи"`
+	WatchdogStallSeconds    int               `envconfig:"WATCHDOG_STALL_SECONDS" default:"30" desc:"Через сколько секунд без heartbeat компонент считается зависшим и /healthz падает (0 — watchdog выключен)"`
+	WatchdogDump            string            `envconfig:"WATCHDOG_DUMP" default:"log" desc:"Куда сохранять стеки горутин при зависании: log, file (в APP_DATA_DIR), none"`
+	Postgres                Postgres          `envconfig:"POSTGRES"`
+
+	sources map[string]Source // откуда пришло каждое значение, см. Sources
 }
 
 type Postgres struct {
-	Host string `envconfig:"HOST" default:"localhost"`
-	Port int    `envconfig:"PORT" default:"5432"`
-	User string `envconfig:"USER" default:""`
-	Pass string `envconfig:"PASSWORD" default:""`
-	DB   string `envconfig:"DB" default:""`
+	// DSN — полная строка подключения (postgres://… или key=value). Если задана, Host, Port, User,
+	// Password и DB не используются; параметры пула и сессии применяются поверх неё, SSLMode и
+	// сертификаты — только если заданы явно.
+	DSN  string `envconfig:"DSN" default:"" sensitive:"true" desc:"Полная строка подключения (URL или key=value); заменяет host/port/user/password/db"`
+	Host string `envconfig:"HOST" default:"localhost" desc:"Хост Postgres"`
+	Port int    `envconfig:"PORT" default:"5432" desc:"Порт Postgres"`
+	User string `envconfig:"USER" default:"" sensitive:"true" desc:"Пользователь Postgres"`
+	Pass string `envconfig:"PASSWORD" default:"" sensitive:"true" desc:"Пароль Postgres"`
+	DB   string `envconfig:"DB" default:"" desc:"База данных Postgres"`
+	// SSLMode — режим TLS libpq: disable, require, verify-ca, verify-full; пустой — см. EffectiveSSLMode.
+	SSLMode string `envconfig:"SSLMODE" default:"" desc:"Режим TLS к Postgres: disable, require, verify-ca, verify-full; по умолчанию sslmode из DSN, без DSN — disable"`
+	// Пути к PEM-файлам (обычно Secret, смонтированный как volume); при изменении файлов пул пересоздаётся.
+	SSLRootCert string `envconfig:"SSLROOTCERT" default:"" desc:"CA bundle (PEM) для проверки сервера; обязателен для verify-ca и verify-full"`
+	SSLCert     string `envconfig:"SSLCERT" default:"" desc:"Клиентский сертификат (PEM) для аутентификации по сертификату"`
+	SSLKey      string `envconfig:"SSLKEY" default:"" desc:"Ключ клиентского сертификата (PEM)"`
+	// ReplicaHosts — read-реплики (host или host:port); остальные параметры подключения как у primary.
+	ReplicaHosts []string `envconfig:"REPLICA_HOSTS" default:"" desc:"Read-реплики через запятую (host или host:port): чтения идут на здоровые реплики, при их недоступности — на primary"`
+
+	// MaxConns и MinConns: 0 — не задано, см. EffectiveMaxConns.
+	MaxConns                 int    `envconfig:"MAX_CONNS" default:"" desc:"Максимум соединений в пуле; по умолчанию pool_max_conns из DSN, без DSN — 5"`
+	MinConns                 int    `envconfig:"MIN_CONNS" default:"" desc:"Минимум соединений, которые пул держит открытыми; по умолчанию pool_min_conns из DSN, иначе 0"`
+	MaxConnIdleSeconds       int    `envconfig:"MAX_CONN_IDLE_SECONDS" default:"120" desc:"Через сколько секунд простоя соединение закрывается"`
+	MaxConnLifetimeSeconds   int    `envconfig:"MAX_CONN_LIFETIME_SECONDS" default:"1800" desc:"Максимальное время жизни соединения, сек"`
+	HealthCheckPeriodSeconds int    `envconfig:"HEALTH_CHECK_PERIOD_SECONDS" default:"60" desc:"Период проверки простаивающих соединений пула, сек"`
+	ConnectTimeoutSeconds    int    `envconfig:"CONNECT_TIMEOUT_SECONDS" default:"5" desc:"Таймаут установки соединения, сек"`
+	StatementTimeoutMs       int    `envconfig:"STATEMENT_TIMEOUT_MS" default:"0" desc:"statement_timeout сессии, мс (0 — без ограничения)"`
+	ApplicationName          string `envconfig:"APPLICATION_NAME" default:"" desc:"application_name в pg_stat_activity (пусто — имя пода APP_POD_NAME)"`
+	SearchPath               string `envconfig:"SEARCH_PATH" default:"" desc:"search_path сессии (пусто — по умолчанию сервера)"`
+}
+
+// Dependencies — зависимости, проверяемые в /readyz; политику каждой задаёт APP_DEPENDENCY_POLICY.
+var Dependencies = []string{"db", "storage", "disk", "vault", "cron"}
+
+// DependencyRequired сообщает, должна ли зависимость быть доступна для готовности пода.
+// Не упомянутые в APP_DEPENDENCY_POLICY зависимости считаются optional.
+func (c Config) DependencyRequired(name string) bool {
+	return c.DependencyPolicy[name] == "required"
+}
+
+// RetentionTables — таблицы, для которых можно задать retention.
+var RetentionTables = []string{"requests", "cron_runs"}
+
+// Retention — ограничения хранения таблицы; нулевое значение не ограничивает.
+type Retention struct {
+	MaxAge  time.Duration
+	MaxRows int64
 }
 
-// Load читает окружение с префиксом APP_.
-func Load() (Config, error) {
-	var c Config
-	if err := envconfig.Process("APP", &c); err != nil {
-		return Config{}, err
+// RetentionPolicies разбирает APP_RETENTION_MAX_AGE и APP_RETENTION_MAX_ROWS. Возраст задаётся
+// как в time.ParseDuration или в днях (30d).
+func (c Config) RetentionPolicies() (map[string]Retention, error) {
+	out := make(map[string]Retention)
+	var errs []error
+	known := func(env, table string) bool {
+		if slices.Contains(RetentionTables, table) {
+			return true
+		}
+		errs = append(errs, fmt.Errorf("%s: unknown table %q, expected one of %s", env, table, strings.Join(RetentionTables, ", ")))
+		return false
+	}
+	for table, v := range c.RetentionMaxAge {
+		if !known("APP_RETENTION_MAX_AGE", table) {
+			continue
+		}
+		d, err := parseAge(v)
+		if err != nil || d <= 0 {
+			errs = append(errs, fmt.Errorf("APP_RETENTION_MAX_AGE: %s: invalid age %q", table, v))
+			continue
+		}
+		r := out[table]
+		r.MaxAge = d
+		out[table] = r
+	}
+	for table, v := range c.RetentionMaxRows {
+		if !known("APP_RETENTION_MAX_ROWS", table) {
+			continue
+		}
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || n <= 0 {
+			errs = append(errs, fmt.Errorf("APP_RETENTION_MAX_ROWS: %s: invalid row count %q", table, v))
+			continue
+		}
+		r := out[table]
+		r.MaxRows = n
+		out[table] = r
+	}
+	return out, errors.Join(errs...)
+}
+
+func parseAge(v string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(v, "d"); ok {
+		n, err := strconv.Atoi(days)
+		return time.Duration(n) * 24 * time.Hour, err
+	}
+	return time.ParseDuration(v)
+}
+
+// Enabled сообщает, задано ли подключение к Postgres (без DSN или пользователя и БД функции БД отключены).
+func (p Postgres) Enabled() bool { return p.DSN != "" || p.User != "" && p.DB != "" }
+
+// EffectiveSSLMode возвращает SSLMode, а если он не задан — disable для подключения по отдельным
+// полям и "" для DSN: тогда действует sslmode из самой строки подключения.
+func (p Postgres) EffectiveSSLMode() string {
+	if p.SSLMode == "" && p.DSN == "" {
+		return "disable"
+	}
+	return p.SSLMode
+}
+
+// DefaultMaxConns — размер пула при подключении по отдельным полям без APP_POSTGRES_MAX_CONNS.
+const DefaultMaxConns = 5
+
+// EffectiveMaxConns возвращает MaxConns, а если он не задан — DefaultMaxConns для подключения по
+// отдельным полям и 0 для DSN: тогда действует pool_max_conns из DSN (или значение pgx по умолчанию).
+func (p Postgres) EffectiveMaxConns() int {
+	if p.MaxConns == 0 && p.DSN == "" {
+		return DefaultMaxConns
 	}
-	return c, nil
+	return p.MaxConns
+}
+
+// TLSFiles возвращает заданные пути к сертификатам и ключу.
+func (p Postgres) TLSFiles() []string {
+	var out []string
+	for _, f := range []string{p.SSLRootCert, p.SSLCert, p.SSLKey} {
+		if f != "" {
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+func (p Postgres) MaxConnIdle() time.Duration {
+	return time.Duration(p.MaxConnIdleSeconds) * time.Second
+}
+func (p Postgres) MaxConnLifetime() time.Duration {
+	return time.Duration(p.MaxConnLifetimeSeconds) * time.Second
+}
+func (p Postgres) HealthCheckPeriod() time.Duration {
+	return time.Duration(p.HealthCheckPeriodSeconds) * time.Second
+}
+func (p Postgres) ConnectTimeout() time.Duration {
+	return time.Duration(p.ConnectTimeoutSeconds) * time.Second
+}
+func (p Postgres) StatementTimeout() time.Duration {
+	return time.Duration(p.StatementTimeoutMs) * time.Millisecond
+}
+
+// withDerived заполняет значения по умолчанию, зависящие от других полей.
+func (c *Config) withDerived() {
+	if c.Postgres.ApplicationName == "" {
+		c.Postgres.ApplicationName = c.PodName
+	}
+}
+
+// loadFileVars подставляет значения sensitive-полей из файлов, указанных в APP_<NAME>_FILE
+// (Secret, смонтированный как volume, или файл, отрендеренный Vault Agent).
+func loadFileVars(c *Config) error {
+	var errs []error
+	for _, f := range fields(c) {
+		if !f.sensitive() {
+			continue
+		}
+		fileEnv := f.Env + "_FILE"
+		path, ok := os.LookupEnv(fileEnv)
+		if !ok || path == "" {
+			continue
+		}
+		if _, set := os.LookupEnv(f.Env); set {
+			errs = append(errs, fmt.Errorf("both %s and %s are set, use only one", f.Env, fileEnv))
+			continue
+		}
+		b, err := os.ReadFile(path)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", fileEnv, err))
+			continue
+		}
+		// редакторы и echo добавляют перевод строки в конец файла — в секрет он не входит
+		f.Value.SetString(strings.TrimRight(string(b), "\r\n"))
+		c.sources[f.Key()] = SourceEnvFile
+	}
+	return errors.Join(errs...)
 }
 
 func (c Config) ReadinessWarmup() time.Duration {
@@ -53,3 +252,24 @@ func (c Config) ReadinessWarmup() time.Duration {
 func (c Config) ShutdownTimeout() time.Duration {
 	return time.Duration(c.ShutdownTimeoutSeconds) * time.Second
 }
+func (c Config) PreDrainDelay() time.Duration {
+	return time.Duration(c.PreDrainDelaySeconds) * time.Second
+}
+func (c Config) ReloadInterval() time.Duration {
+	return time.Duration(c.ReloadIntervalSeconds) * time.Second
+}
+func (c Config) HealthInterval() time.Duration {
+	return time.Duration(c.HealthIntervalSeconds) * time.Second
+}
+func (c Config) CronStaleAfter() time.Duration {
+	return time.Duration(c.CronStaleAfterSeconds) * time.Second
+}
+func (c Config) RequestsBatchFlush() time.Duration {
+	return time.Duration(c.RequestsBatchIntervalMs) * time.Millisecond
+}
+func (c Config) RollupLookback() time.Duration {
+	return time.Duration(c.RollupLookbackMinutes) * time.Minute
+}
+func (c Config) WatchdogStall() time.Duration {
+	return time.Duration(c.WatchdogStallSeconds) * time.Second
+}
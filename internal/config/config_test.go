@@ -0,0 +1,263 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeFile(t *testing.T, content string) string {
+	t.Helper()
+	p := filepath.Join(t.TempDir(), "secret")
+	if err := os.WriteFile(p, []byte(content), 0o600); err != nil {
+		t.Fatalf("write file: %v", err)
+	}
+	return p
+}
+
+func TestLoadFileVars(t *testing.T) {
+	t.Setenv("APP_SECRET_PASSWORD_FILE", writeFile(t, "s3cret\n"))
+	t.Setenv("APP_POSTGRES_PASSWORD_FILE", writeFile(t, "pgpass"))
+	t.Setenv("APP_POSTGRES_USER", "lamarr")
+	c, err := Load()
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if c.SecretPassword != "s3cret" {
+		t.Fatalf("unexpected secret password: %q", c.SecretPassword)
+	}
+	if c.Postgres.Pass != "pgpass" || c.Postgres.User != "lamarr" {
+		t.Fatalf("unexpected postgres credentials: %+v", c.Postgres)
+	}
+	src := c.Sources()
+	if src["postgres.password"] != SourceEnvFile || src["postgres.user"] != SourceEnv {
+		t.Fatalf("unexpected sources: password=%s user=%s", src["postgres.password"], src["postgres.user"])
+	}
+}
+
+func TestLoadFileVarsConflict(t *testing.T) {
+	t.Setenv("APP_SECRET_PASSWORD", "inline")
+	t.Setenv("APP_SECRET_PASSWORD_FILE", writeFile(t, "from-file"))
+	t.Setenv("APP_POSTGRES_USER_FILE", filepath.Join(t.TempDir(), "missing"))
+	_, err := Load()
+	if err == nil {
+		t.Fatalf("expected error for conflicting variables")
+	}
+	for _, want := range []string{"APP_SECRET_PASSWORD_FILE", "APP_POSTGRES_USER_FILE"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Fatalf("error %q does not mention %s", err, want)
+		}
+	}
+}
+
+func TestApplyDirs(t *testing.T) {
+	// эмулируем том ConfigMap: ключ -> ..data/ключ, ..data -> ..timestamp
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, "..2026_01_01"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "..2026_01_01", "APP_CONFIG_MAP_ENV_VAR"), []byte("from-volume\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("..2026_01_01", filepath.Join(dir, "..data")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("..data/APP_CONFIG_MAP_ENV_VAR", filepath.Join(dir, "APP_CONFIG_MAP_ENV_VAR")); err != nil {
+		t.Fatal(err)
+	}
+	base, err := Load()
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	base.ConfigMapEnvVar = "from-env"
+	base.WatchDirs = []string{dir}
+	c, err := base.ApplyDirs()
+	if err != nil {
+		t.Fatalf("apply dirs: %v", err)
+	}
+	if c.ConfigMapEnvVar != "from-volume" {
+		t.Fatalf("unexpected value: %q", c.ConfigMapEnvVar)
+	}
+	if base.ConfigMapEnvVar != "from-env" {
+		t.Fatalf("base config modified: %q", base.ConfigMapEnvVar)
+	}
+}
+
+func TestLoadArgsLayers(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "app.yaml")
+	yml := "port: \"9000\"\nshutdown_timeout_seconds: 30\nconfig_map_env_var: from-file\npostgres:\n  host: db.local\n  port: 6432\n"
+	if err := os.WriteFile(file, []byte(yml), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("APP_CONFIG_FILE", file)
+	t.Setenv("APP_SHUTDOWN_TIMEOUT_SECONDS", "20")
+	t.Setenv("APP_POSTGRES_HOST", "env.local")
+	c, err := LoadArgs([]string{"-postgres-host", "flag.local"})
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if c.ReadinessWarmupSeconds != 1 { // default
+		t.Fatalf("unexpected warmup: %d", c.ReadinessWarmupSeconds)
+	}
+	if c.Port != "9000" || c.ConfigMapEnvVar != "from-file" || c.Postgres.Port != 6432 { // file
+		t.Fatalf("file layer not applied: %+v", c)
+	}
+	if c.ShutdownTimeoutSeconds != 20 { // env поверх файла
+		t.Fatalf("unexpected shutdown timeout: %d", c.ShutdownTimeoutSeconds)
+	}
+	if c.Postgres.Host != "flag.local" { // флаг поверх env
+		t.Fatalf("unexpected postgres host: %s", c.Postgres.Host)
+	}
+	src := c.Sources()
+	want := map[string]Source{
+		"readiness_warmup_seconds": SourceDefault,
+		"port":                     SourceFile,
+		"shutdown_timeout_seconds": SourceEnv,
+		"postgres.host":            SourceFlag,
+	}
+	for k, v := range want {
+		if src[k] != v {
+			t.Fatalf("source of %s = %q, want %q", k, src[k], v)
+		}
+	}
+}
+
+func TestValidateListsAllProblems(t *testing.T) {
+	t.Setenv("APP_PORT", "abc")
+	t.Setenv("APP_SHUTDOWN_TIMEOUT_SECONDS", "-1")
+	t.Setenv("APP_SECRET_MASKS", "token:md5")
+	t.Setenv("APP_HEALTH_INTERVAL_SECONDS", "abc") // ошибка разбора не скрывает нарушения Validate
+	_, err := Load()
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("expected ValidationError, got %v", err)
+	}
+	if len(verr.Problems) != 4 || !strings.HasPrefix(verr.Problems[0], "APP_HEALTH_INTERVAL_SECONDS: ") {
+		t.Fatalf("expected 4 problems starting with the parse error, got %d: %v", len(verr.Problems), verr)
+	}
+}
+
+func TestEveryFieldDocumented(t *testing.T) {
+	for _, f := range docFields() {
+		if f.Tag.Get("desc") == "" {
+			t.Errorf("%s: missing desc tag (used by `app config-docs`)", f.Env)
+		}
+	}
+}
+
+func TestGeneratedDocsUpToDate(t *testing.T) {
+	for path, gen := range map[string]func(io.Writer) error{
+		"../../docs/config.md":                             WriteMarkdown,
+		"../../.env.example":                               WriteEnvExample,
+		"../../helm/app/charts/backend/values.schema.json": WriteHelmSchema,
+	} {
+		want, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got bytes.Buffer
+		if err := gen(&got); err != nil {
+			t.Fatal(err)
+		}
+		if got.String() != string(want) {
+			t.Errorf("%s is out of date, run `make config-docs`", path)
+		}
+	}
+}
+
+func TestProdProfileViolations(t *testing.T) {
+	t.Setenv("APP_PROFILE", "prod")
+	t.Setenv("APP_POSTGRES_USER", "lamarr")
+	t.Setenv("APP_POSTGRES_DB", "db")
+	_, err := Load()
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("expected ValidationError, got %v", err)
+	}
+	for _, env := range []string{"APP_POSTGRES_SSLMODE", "APP_SECRET_PASSWORD", "APP_POSTGRES_PASSWORD", "APP_ADMIN_TOKEN", "APP_SWAGGER_ENABLED"} {
+		if !strings.Contains(verr.Error(), env+":") {
+			t.Errorf("expected violation for %s in:\n%v", env, verr)
+		}
+	}
+
+	t.Setenv("APP_POSTGRES_SSLMODE", "verify-full")
+	t.Setenv("APP_POSTGRES_SSLROOTCERT", "/etc/pg-tls/ca.crt")
+	t.Setenv("APP_POSTGRES_PASSWORD", "qwerty12345")
+	t.Setenv("APP_SECRET_USERNAME", "developer")
+	t.Setenv("APP_SECRET_PASSWORD", "password")
+	t.Setenv("APP_ADMIN_TOKEN", "token")
+	t.Setenv("APP_SWAGGER_ENABLED", "false")
+	if _, err := Load(); err != nil {
+		t.Fatalf("expected valid prod config, got %v", err)
+	}
+}
+
+func TestProdProfileSecretsFromWatchDirs(t *testing.T) {
+	dir := t.TempDir()
+	for name, v := range map[string]string{"APP_SECRET_USERNAME": "developer\n", "APP_SECRET_PASSWORD": "password\n", "APP_ADMIN_TOKEN": "token\n"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(v), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Setenv("APP_PROFILE", "prod")
+	t.Setenv("APP_SWAGGER_ENABLED", "false")
+	t.Setenv("APP_WATCH_DIRS", dir)
+	base, err := LoadArgs(nil)
+	if err != nil {
+		t.Fatalf("prod rules must wait for APP_WATCH_DIRS: %v", err)
+	}
+	if _, err := base.ApplyDirs(); err != nil {
+		t.Fatalf("secrets from APP_WATCH_DIRS not taken into account: %v", err)
+	}
+
+	if err := os.Remove(filepath.Join(dir, "APP_ADMIN_TOKEN")); err != nil {
+		t.Fatal(err)
+	}
+	_, err = base.ApplyDirs()
+	if err == nil || !strings.Contains(err.Error(), "APP_ADMIN_TOKEN:") {
+		t.Fatalf("expected prod violation for APP_ADMIN_TOKEN, got %v", err)
+	}
+}
+
+func TestPostgresPoolSettings(t *testing.T) {
+	t.Setenv("APP_POD_NAME", "backend-7d9f")
+	c, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Postgres.ApplicationName != "backend-7d9f" {
+		t.Fatalf("application_name should default to pod name, got %q", c.Postgres.ApplicationName)
+	}
+
+	t.Setenv("APP_POSTGRES_MAX_CONNS", "2")
+	t.Setenv("APP_POSTGRES_MIN_CONNS", "4")
+	_, err = Load()
+	if err == nil || !strings.Contains(err.Error(), "APP_POSTGRES_MIN_CONNS:") {
+		t.Fatalf("expected APP_POSTGRES_MIN_CONNS violation, got %v", err)
+	}
+}
+
+func TestRetentionPolicies(t *testing.T) {
+	t.Setenv("APP_RETENTION_MAX_AGE", "requests:30d,cron_runs:168h")
+	t.Setenv("APP_RETENTION_MAX_ROWS", "requests:1000")
+	c, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, _ := c.RetentionPolicies()
+	if got["requests"] != (Retention{MaxAge: 30 * 24 * time.Hour, MaxRows: 1000}) || got["cron_runs"] != (Retention{MaxAge: 168 * time.Hour}) {
+		t.Fatalf("unexpected policies: %+v", got)
+	}
+
+	t.Setenv("APP_RETENTION_MAX_AGE", "events:1d,cron_runs:soon")
+	_, err = Load()
+	var verr *ValidationError
+	if !errors.As(err, &verr) || len(verr.Problems) != 2 {
+		t.Fatalf("expected unknown table and invalid age, got %v", err)
+	}
+}
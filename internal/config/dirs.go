@@ -0,0 +1,93 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"k8s-hw/internal/watch"
+)
+
+// ApplyDirs возвращает копию c, поверх которой наложены значения из файлов каталогов c.WatchDirs.
+// Имя файла должно совпадать с именем переменной окружения (APP_CONFIG_MAP_ENV_VAR,
+// APP_POSTGRES_PASSWORD, ...): ConfigMap с такими ключами монтируется как есть, для Secret
+// ключи переименовываются через items[].path. При совпадении ключей побеждает последний каталог.
+func (c Config) ApplyDirs() (Config, error) {
+	files := make(map[string]string)
+	for _, dir := range c.WatchDirs {
+		m, err := watch.ReadDir(dir)
+		if err != nil {
+			return c, fmt.Errorf("read %s: %w", dir, err)
+		}
+		for k, v := range m {
+			files[k] = v
+		}
+	}
+	out := c
+	// срезы разделяются между копиями — отвязываем их до записи
+	out.WatchDirs = append([]string(nil), c.WatchDirs...)
+	out.sources = make(map[string]Source, len(c.sources))
+	for k, v := range c.sources {
+		out.sources[k] = v
+	}
+	var errs []error
+	for _, f := range fields(&out) {
+		v, ok := files[f.Env]
+		if !ok || f.Env == "APP_WATCH_DIRS" {
+			continue
+		}
+		out.sources[f.Key()] = SourceVolume
+		if err := setValue(f.Value, strings.TrimRight(v, "\r\n")); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", f.Env, err))
+		}
+	}
+	if err := joinProblems(errs, out.Validate()); err != nil {
+		return c, err
+	}
+	return out, nil
+}
+
+// setValue разбирает строковое значение в поле поддерживаемого типа.
+func setValue(v reflect.Value, s string) error {
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Int, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", s)
+		}
+		v.SetInt(n)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return fmt.Errorf("invalid bool %q", s)
+		}
+		v.SetBool(b)
+	case reflect.Slice:
+		var items []string
+		for _, it := range strings.Split(s, ",") {
+			if it = strings.TrimSpace(it); it != "" {
+				items = append(items, it)
+			}
+		}
+		v.Set(reflect.ValueOf(items))
+	case reflect.Map:
+		m := make(map[string]string)
+		for _, it := range strings.Split(s, ",") {
+			if it = strings.TrimSpace(it); it == "" {
+				continue
+			}
+			k, val, ok := strings.Cut(it, ":")
+			if !ok {
+				return fmt.Errorf("invalid map item %q, want key:value", it)
+			}
+			m[strings.TrimSpace(k)] = strings.TrimSpace(val)
+		}
+		v.Set(reflect.ValueOf(m))
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+)
+
+// Генерация документации по конфигурации из тегов Config — единственный источник правды
+// для таблицы переменных, .env-примера и JSON schema значений Helm-чарта.
+
+const generatedNote = "Сгенерировано `app config-docs`, не редактировать вручную."
+
+// typeName — тип поля в терминах переменных окружения.
+func typeName(f field) string {
+	switch f.Value.Kind() {
+	case reflect.Int, reflect.Int64:
+		return "int"
+	case reflect.Bool:
+		return "bool"
+	case reflect.Slice:
+		return "list"
+	case reflect.Map:
+		return "map"
+	default:
+		return "string"
+	}
+}
+
+func docFields() []field {
+	var c Config
+	return fields(&c)
+}
+
+// WriteMarkdown пишет таблицу переменных окружения в Markdown.
+func WriteMarkdown(w io.Writer) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "<!-- %s -->\n\n", generatedNote)
+	b.WriteString("| Переменная | Тип | Назначение | По умолчанию |\n")
+	b.WriteString("|-----------|-----|------------|--------------|\n")
+	for _, f := range docFields() {
+		def := f.Tag.Get("default")
+		if def == "" {
+			def = "(пусто)"
+		}
+		desc := f.Tag.Get("desc")
+		if f.sensitive() {
+			desc += fmt.Sprintf(" — секрет, можно передать файлом `%s_FILE`", f.Env)
+		}
+		fmt.Fprintf(&b, "| %s | %s | %s | %s |\n", f.Env, typeName(f), desc, def)
+	}
+	b.WriteString("\nСписки задаются через запятую (`a,b`), map — парами `key:value` через запятую.\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// WriteEnvExample пишет пример .env со значениями по умолчанию. Секреты закомментированы.
+func WriteEnvExample(w io.Writer) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n", generatedNote)
+	for _, f := range docFields() {
+		fmt.Fprintf(&b, "\n# %s (%s)\n", f.Tag.Get("desc"), typeName(f))
+		if f.sensitive() {
+			fmt.Fprintf(&b, "# %s=\n# %s_FILE=\n", f.Env, f.Env)
+			continue
+		}
+		fmt.Fprintf(&b, "%s=%s\n", f.Env, f.Tag.Get("default"))
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// WriteHelmSchema пишет values.schema.json для чарта backend: описывает env.configmap,
+// откуда переменные попадают в ConfigMap. Секретов в ConfigMap быть не должно, поэтому
+// для них (как и для неизвестных ключей) схема не пропустит значения.
+func WriteHelmSchema(w io.Writer) error {
+	props := map[string]any{}
+	for _, f := range docFields() {
+		if f.sensitive() {
+			continue
+		}
+		p := map[string]any{
+			"type":        []string{"string", "number", "boolean"},
+			"description": f.Tag.Get("desc"),
+		}
+		if def := f.Tag.Get("default"); def != "" {
+			p["default"] = def
+		}
+		switch typeName(f) {
+		case "int":
+			p["type"] = []string{"string", "integer"}
+			p["pattern"] = `^-?[0-9]+$`
+		case "bool":
+			p["type"] = []string{"string", "boolean"}
+			p["pattern"] = `^(true|false)$`
+		}
+		props[f.Env] = p
+	}
+	schema := map[string]any{
+		"$schema":  "https://json-schema.org/draft-07/schema#",
+		"$comment": generatedNote,
+		"type":     "object",
+		"properties": map[string]any{
+			"env": map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"configmap": map[string]any{
+						"type":                 "object",
+						"additionalProperties": false,
+						"properties":           props,
+					},
+				},
+			},
+		},
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+	return enc.Encode(schema)
+}
@@ -0,0 +1,50 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+)
+
+// field — одно конечное (не struct) поле Config с вычисленными именами для всех слоёв конфигурации.
+type field struct {
+	Env   string   // переменная окружения, например APP_POSTGRES_PASSWORD
+	Path  []string // путь в файле конфигурации, например [postgres password]
+	Value reflect.Value
+	Tag   reflect.StructTag
+}
+
+// Key — ключ поля в файле конфигурации: postgres.password.
+func (f field) Key() string { return strings.Join(f.Path, ".") }
+
+// Flag — имя флага командной строки: -postgres-password.
+func (f field) Flag() string {
+	return strings.ReplaceAll(strings.Join(f.Path, "-"), "_", "-")
+}
+
+// sensitive сообщает, содержит ли поле секрет (тег sensitive:"true").
+func (f field) sensitive() bool { return f.Tag.Get("sensitive") == "true" }
+
+// fields обходит Config (включая вложенные структуры) в порядке объявления полей.
+func fields(c *Config) []field {
+	var out []field
+	walk("APP", nil, reflect.ValueOf(c).Elem(), &out)
+	return out
+}
+
+func walk(prefix string, path []string, v reflect.Value, out *[]field) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		name := sf.Tag.Get("envconfig")
+		if !sf.IsExported() || name == "" {
+			continue
+		}
+		env := prefix + "_" + strings.ToUpper(name)
+		p := append(append([]string(nil), path...), strings.ToLower(name))
+		if sf.Type.Kind() == reflect.Struct {
+			walk(env, p, v.Field(i), out)
+			continue
+		}
+		*out = append(*out, field{Env: env, Path: p, Value: v.Field(i), Tag: sf.Tag})
+	}
+}
@@ -0,0 +1,261 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Source — слой, из которого пришло значение поля.
+type Source string
+
+const (
+	SourceDefault Source = "default"
+	SourceFile    Source = "file"
+	SourceEnv     Source = "env"
+	SourceEnvFile Source = "env-file" // файл из APP_<NAME>_FILE
+	SourceFlag    Source = "flag"
+	SourceVolume  Source = "volume" // каталоги APP_WATCH_DIRS (hot reload)
+)
+
+// ErrConfigPrinted возвращается LoadArgs, если был передан -print-config: конфигурация
+// уже выведена в stdout, запускать сервис не нужно.
+var ErrConfigPrinted = errors.New("config printed")
+
+// Load собирает итоговую конфигурацию без флагов командной строки (defaults -> файл -> APP_* env
+// -> каталоги APP_WATCH_DIRS).
+func Load() (Config, error) {
+	base, err := LoadArgs(nil)
+	if err != nil {
+		return base, err
+	}
+	return base.ApplyDirs()
+}
+
+// LoadArgs собирает конфигурацию по слоям, каждый следующий перекрывает предыдущий:
+//  1. значения из тегов default;
+//  2. файл YAML/JSON из -config или APP_CONFIG_FILE (ключи — как в -print-config);
+//  3. переменные окружения APP_* (и APP_*_FILE для секретов);
+//  4. флаги командной строки (-port, -postgres-host, ...).
+//
+// Результат проходит Validate, кроме правил профиля prod: их проверяет ApplyDirs по итоговой
+// конфигурации, так как секреты могут прийти только из каталогов APP_WATCH_DIRS.
+func LoadArgs(args []string) (Config, error) {
+	var c Config
+	all := fields(&c)
+
+	fs := flag.NewFlagSet("app", flag.ContinueOnError)
+	configFile := fs.String("config", os.Getenv("APP_CONFIG_FILE"), "path to YAML/JSON config file (env APP_CONFIG_FILE)")
+	printConfig := fs.Bool("print-config", false, "print effective config (secrets redacted) and exit")
+	flagVals := make(map[string]string)
+	for _, f := range all {
+		fs.Func(f.Flag(), fmt.Sprintf("overrides %s", f.Env), func(s string) error {
+			flagVals[f.Env] = s
+			return nil
+		})
+	}
+	if err := fs.Parse(args); err != nil {
+		return Config{}, err
+	}
+
+	var errs []error
+	c.sources = make(map[string]Source, len(all))
+	for _, f := range all {
+		c.sources[f.Key()] = SourceDefault
+		if def := f.Tag.Get("default"); def != "" {
+			if err := setValue(f.Value, def); err != nil {
+				errs = append(errs, fmt.Errorf("default %s: %w", f.Env, err))
+			}
+		}
+	}
+	if *configFile != "" {
+		if err := applyFile(all, *configFile, c.sources); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	for _, f := range all {
+		if v, ok := os.LookupEnv(f.Env); ok {
+			c.sources[f.Key()] = SourceEnv
+			if err := setValue(f.Value, v); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", f.Env, err))
+			}
+		}
+	}
+	if err := loadFileVars(&c); err != nil {
+		errs = append(errs, err)
+	}
+	for _, f := range all {
+		if v, ok := flagVals[f.Env]; ok {
+			c.sources[f.Key()] = SourceFlag
+			if err := setValue(f.Value, v); err != nil {
+				errs = append(errs, fmt.Errorf("-%s: %w", f.Flag(), err))
+			}
+		}
+	}
+	// ошибки разбора и нарушения Validate (по полям, которые разобрались) — одним списком
+	c.withDerived()
+	if err := joinProblems(errs, c.validate(false)); err != nil {
+		return Config{}, err
+	}
+	if *printConfig {
+		if err := Print(os.Stdout, c); err != nil {
+			return Config{}, err
+		}
+		return c, ErrConfigPrinted
+	}
+	return c, nil
+}
+
+// joinProblems собирает ошибки разбора слоёв и результат Validate в один *ValidationError.
+func joinProblems(parse []error, validate error) error {
+	var p []string
+	var flatten func(err error)
+	flatten = func(err error) {
+		if j, ok := err.(interface{ Unwrap() []error }); ok {
+			for _, e := range j.Unwrap() {
+				flatten(e)
+			}
+			return
+		}
+		p = append(p, err.Error())
+	}
+	for _, err := range parse {
+		flatten(err)
+	}
+	var verr *ValidationError
+	if errors.As(validate, &verr) {
+		p = append(p, verr.Problems...)
+	} else if validate != nil {
+		p = append(p, validate.Error())
+	}
+	if len(p) == 0 {
+		return nil
+	}
+	return &ValidationError{Problems: p}
+}
+
+// applyFile накладывает значения из YAML/JSON файла. Ключи — вложенные имена полей
+// в нижнем регистре (postgres: {host: ...}); '-' и '_' взаимозаменяемы. Неизвестные ключи — ошибка.
+func applyFile(all []field, path string, sources map[string]Source) error {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("config file: %w", err)
+	}
+	raw := map[string]any{}
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		err = json.Unmarshal(b, &raw)
+	} else {
+		err = yaml.Unmarshal(b, &raw)
+	}
+	if err != nil {
+		return fmt.Errorf("config file %s: %w", path, err)
+	}
+	byKey := make(map[string]field, len(all))
+	for _, f := range all {
+		byKey[f.Key()] = f
+	}
+	var errs []error
+	var visit func(prefix string, m map[string]any)
+	visit = func(prefix string, m map[string]any) {
+		for k, v := range m {
+			key := strings.ReplaceAll(strings.ToLower(k), "-", "_")
+			if prefix != "" {
+				key = prefix + "." + key
+			}
+			if f, ok := byKey[key]; ok {
+				sources[key] = SourceFile
+				if err := setValue(f.Value, scalarString(v)); err != nil {
+					errs = append(errs, fmt.Errorf("config file %s: %s: %w", path, key, err))
+				}
+				continue
+			}
+			if sub, ok := v.(map[string]any); ok {
+				visit(key, sub)
+				continue
+			}
+			errs = append(errs, fmt.Errorf("config file %s: unknown key %q", path, key))
+		}
+	}
+	visit("", raw)
+	return errors.Join(errs...)
+}
+
+// scalarString приводит значение из файла к строковому виду, понятному setValue.
+func scalarString(v any) string {
+	switch t := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return t
+	case float64:
+		return strconv.FormatFloat(t, 'f', -1, 64)
+	case []any:
+		parts := make([]string, 0, len(t))
+		for _, it := range t {
+			parts = append(parts, scalarString(it))
+		}
+		return strings.Join(parts, ",")
+	case map[string]any:
+		parts := make([]string, 0, len(t))
+		for k, it := range t {
+			parts = append(parts, k+":"+scalarString(it))
+		}
+		sort.Strings(parts)
+		return strings.Join(parts, ",")
+	default:
+		return fmt.Sprint(t)
+	}
+}
+
+// Print выводит конфигурацию в формате, пригодном для -config (JSON), с замаскированными секретами.
+func Print(w io.Writer, c Config) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(c.Tree())
+}
+
+// Sources возвращает слой-источник для каждого ключа конфигурации (postgres.host -> env).
+// Для Config, собранного вручную (тесты), все значения считаются SourceDefault.
+func (c Config) Sources() map[string]Source {
+	out := make(map[string]Source)
+	for _, f := range fields(&c) {
+		src, ok := c.sources[f.Key()]
+		if !ok {
+			src = SourceDefault
+		}
+		out[f.Key()] = src
+	}
+	return out
+}
+
+// Tree возвращает конфигурацию как вложенную map по ключам файла конфигурации.
+// Значения sensitive-полей заменяются на "***" (пустые остаются пустыми).
+func (c Config) Tree() map[string]any {
+	root := map[string]any{}
+	for _, f := range fields(&c) {
+		m := root
+		for _, p := range f.Path[:len(f.Path)-1] {
+			next, ok := m[p].(map[string]any)
+			if !ok {
+				next = map[string]any{}
+				m[p] = next
+			}
+			m = next
+		}
+		var v any = f.Value.Interface()
+		if f.sensitive() && !f.Value.IsZero() {
+			v = "***"
+		}
+		m[f.Path[len(f.Path)-1]] = v
+	}
+	return root
+}
@@ -0,0 +1,226 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"k8s-hw/internal/mask"
+)
+
+// Профили окружения (APP_PROFILE).
+const (
+	ProfileDev     = "dev"
+	ProfileStaging = "staging"
+	ProfileProd    = "prod"
+)
+
+// ValidationError перечисляет все некорректные поля конфигурации сразу, а не только первое.
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return "invalid config:\n  - " + strings.Join(e.Problems, "\n  - ")
+}
+
+// Validate проверяет значения конфигурации и возвращает *ValidationError со всеми нарушениями.
+func (c Config) Validate() error { return c.validate(true) }
+
+// validate проверяет значения; правила профиля prod — только при profileRules: до наложения
+// APP_WATCH_DIRS секреты из смонтированных Secret ещё пусты.
+func (c Config) validate(profileRules bool) error {
+	var p []string
+	add := func(env, format string, args ...any) {
+		p = append(p, env+": "+fmt.Sprintf(format, args...))
+	}
+	if n, err := strconv.Atoi(c.Port); err != nil || n < 1 || n > 65535 {
+		add("APP_PORT", "must be a port number 1..65535, got %q", c.Port)
+	}
+	if c.ReadinessWarmupSeconds < 0 {
+		add("APP_READINESS_WARMUP_SECONDS", "must be >= 0, got %d", c.ReadinessWarmupSeconds)
+	}
+	if c.ShutdownTimeoutSeconds <= 0 {
+		add("APP_SHUTDOWN_TIMEOUT_SECONDS", "must be > 0, got %d", c.ShutdownTimeoutSeconds)
+	}
+	if c.PreDrainDelaySeconds < 0 {
+		add("APP_PRE_DRAIN_DELAY_SECONDS", "must be >= 0, got %d", c.PreDrainDelaySeconds)
+	}
+	if c.ReloadIntervalSeconds <= 0 {
+		add("APP_RELOAD_INTERVAL_SECONDS", "must be > 0, got %d", c.ReloadIntervalSeconds)
+	}
+	if c.HealthIntervalSeconds <= 0 {
+		add("APP_HEALTH_INTERVAL_SECONDS", "must be > 0, got %d", c.HealthIntervalSeconds)
+	}
+	if c.HealthFailureThreshold < 1 {
+		add("APP_HEALTH_FAILURE_THRESHOLD", "must be >= 1, got %d", c.HealthFailureThreshold)
+	}
+	if c.HealthSuccessThreshold < 1 {
+		add("APP_HEALTH_SUCCESS_THRESHOLD", "must be >= 1, got %d", c.HealthSuccessThreshold)
+	}
+	if c.CronStaleAfterSeconds < 0 {
+		add("APP_CRON_STALE_AFTER_SECONDS", "must be >= 0, got %d", c.CronStaleAfterSeconds)
+	}
+	if c.DiskMinFreeMB < 0 {
+		add("APP_DISK_MIN_FREE_MB", "must be >= 0, got %d", c.DiskMinFreeMB)
+	}
+	if c.RequestsWriteMode != "sync" && c.RequestsWriteMode != "async" {
+		add("APP_REQUESTS_WRITE_MODE", "must be sync or async, got %q", c.RequestsWriteMode)
+	}
+	if c.RequestsBatchSize < 1 {
+		add("APP_REQUESTS_BATCH_SIZE", "must be >= 1, got %d", c.RequestsBatchSize)
+	}
+	if c.RequestsBatchIntervalMs < 1 {
+		add("APP_REQUESTS_BATCH_INTERVAL_MS", "must be >= 1, got %d", c.RequestsBatchIntervalMs)
+	}
+	if c.RequestsQueueSize < c.RequestsBatchSize {
+		add("APP_REQUESTS_QUEUE_SIZE", "must be >= APP_REQUESTS_BATCH_SIZE (%d), got %d", c.RequestsBatchSize, c.RequestsQueueSize)
+	}
+	if _, err := c.RetentionPolicies(); err != nil {
+		msgs := strings.Split(err.Error(), "\n")
+		sort.Strings(msgs)
+		p = append(p, msgs...)
+	}
+	if c.PartitionsAheadMonths < 0 {
+		add("APP_PARTITIONS_AHEAD_MONTHS", "must be >= 0, got %d", c.PartitionsAheadMonths)
+	}
+	if c.RetentionBatchSize < 1 {
+		add("APP_RETENTION_BATCH_SIZE", "must be >= 1, got %d", c.RetentionBatchSize)
+	}
+	if c.RollupLookbackMinutes < 0 {
+		add("APP_ROLLUP_LOOKBACK_MINUTES", "must be >= 0, got %d", c.RollupLookbackMinutes)
+	}
+	if c.WatchdogStallSeconds < 0 {
+		add("APP_WATCHDOG_STALL_SECONDS", "must be >= 0, got %d", c.WatchdogStallSeconds)
+	}
+	if !slices.Contains([]string{"log", "file", "none"}, c.WatchdogDump) {
+		add("APP_WATCHDOG_DUMP", "must be one of log, file, none, got %q", c.WatchdogDump)
+	}
+	if _, err := mask.Parse(c.SecretMaskDefault); err != nil {
+		add("APP_SECRET_MASK_DEFAULT", "%v", err)
+	}
+	keys := make([]string, 0, len(c.SecretMasks))
+	for k := range c.SecretMasks {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if _, err := mask.Parse(c.SecretMasks[k]); err != nil {
+			add("APP_SECRET_MASKS", "key %s: %v", k, err)
+		}
+	}
+	policyKeys := make([]string, 0, len(c.DependencyPolicy))
+	for k := range c.DependencyPolicy {
+		policyKeys = append(policyKeys, k)
+	}
+	sort.Strings(policyKeys)
+	for _, k := range policyKeys {
+		if !slices.Contains(Dependencies, k) {
+			add("APP_DEPENDENCY_POLICY", "unknown dependency %q (want one of %s)", k, strings.Join(Dependencies, ", "))
+		}
+		if v := c.DependencyPolicy[k]; v != "required" && v != "optional" {
+			add("APP_DEPENDENCY_POLICY", "%s: policy must be required or optional, got %q", k, v)
+		}
+	}
+	if c.DataDir == "" {
+		add("APP_DATA_DIR", "must not be empty")
+	}
+	pg := c.Postgres
+	if pg.Port < 1 || pg.Port > 65535 {
+		add("APP_POSTGRES_PORT", "must be a port number 1..65535, got %d", pg.Port)
+	}
+	for _, hp := range pg.ReplicaHosts {
+		if !strings.Contains(hp, ":") {
+			continue
+		}
+		if _, port, err := net.SplitHostPort(hp); err != nil {
+			add("APP_POSTGRES_REPLICA_HOSTS", "%q: %v", hp, err)
+		} else if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+			add("APP_POSTGRES_REPLICA_HOSTS", "%q: port must be 1..65535", hp)
+		}
+	}
+	if pg.MaxConns < 0 {
+		add("APP_POSTGRES_MAX_CONNS", "must be >= 1 (0 — not set), got %d", pg.MaxConns)
+	}
+	if maxConns := pg.EffectiveMaxConns(); pg.MinConns < 0 || maxConns > 0 && pg.MinConns > maxConns {
+		add("APP_POSTGRES_MIN_CONNS", "must be between 0 and APP_POSTGRES_MAX_CONNS (%d), got %d", maxConns, pg.MinConns)
+	}
+	for _, d := range []struct {
+		env string
+		v   int
+	}{
+		{"APP_POSTGRES_MAX_CONN_IDLE_SECONDS", pg.MaxConnIdleSeconds},
+		{"APP_POSTGRES_MAX_CONN_LIFETIME_SECONDS", pg.MaxConnLifetimeSeconds},
+		{"APP_POSTGRES_HEALTH_CHECK_PERIOD_SECONDS", pg.HealthCheckPeriodSeconds},
+		{"APP_POSTGRES_CONNECT_TIMEOUT_SECONDS", pg.ConnectTimeoutSeconds},
+	} {
+		if d.v <= 0 {
+			add(d.env, "must be > 0, got %d", d.v)
+		}
+	}
+	if pg.StatementTimeoutMs < 0 {
+		add("APP_POSTGRES_STATEMENT_TIMEOUT_MS", "must be >= 0, got %d", pg.StatementTimeoutMs)
+	}
+	switch c.Postgres.SSLMode {
+	case "", "disable", "require", "verify-ca", "verify-full":
+	default:
+		add("APP_POSTGRES_SSLMODE", "must be one of disable, require, verify-ca, verify-full, got %q", c.Postgres.SSLMode)
+	}
+	if (pg.SSLMode == "verify-ca" || pg.SSLMode == "verify-full") && pg.SSLRootCert == "" {
+		add("APP_POSTGRES_SSLROOTCERT", "CA bundle is required for sslmode %s", pg.SSLMode)
+	}
+	if (pg.SSLCert == "") != (pg.SSLKey == "") {
+		add("APP_POSTGRES_SSLCERT", "client certificate and key (APP_POSTGRES_SSLKEY) must be set together")
+	}
+	if pg.EffectiveSSLMode() == "disable" && len(pg.TLSFiles()) > 0 {
+		add("APP_POSTGRES_SSLMODE", "certificates are set but sslmode is disable")
+	}
+	switch c.Profile {
+	case ProfileDev, ProfileStaging:
+	case ProfileProd:
+		if profileRules {
+			p = append(p, c.prodViolations()...)
+		}
+	default:
+		add("APP_PROFILE", "must be one of dev, staging, prod, got %q", c.Profile)
+	}
+	if len(p) > 0 {
+		return &ValidationError{Problems: p}
+	}
+	return nil
+}
+
+// prodViolations — правила профиля prod: шифрование до БД, заданные секреты,
+// выключенный Swagger UI и закрытые токеном служебные эндпоинты.
+func (c Config) prodViolations() []string {
+	var p []string
+	add := func(env, format string, args ...any) {
+		p = append(p, env+": "+fmt.Sprintf(format, args...)+" (profile prod)")
+	}
+	if c.Postgres.Enabled() && c.Postgres.EffectiveSSLMode() == "disable" {
+		add("APP_POSTGRES_SSLMODE", "TLS to Postgres is required, set require, verify-ca or verify-full")
+	}
+	for _, f := range fields(&c) {
+		if !f.sensitive() || !f.Value.IsZero() {
+			continue
+		}
+		switch {
+		case f.Env == "APP_ADMIN_TOKEN":
+			add(f.Env, "admin endpoints must be protected, token must not be empty")
+		case strings.HasPrefix(f.Env, "APP_POSTGRES_") && !c.Postgres.Enabled():
+			// БД не используется — учётные данные не нужны
+		case f.Env == "APP_POSTGRES_DSN" && c.Postgres.Enabled(),
+			(f.Env == "APP_POSTGRES_USER" || f.Env == "APP_POSTGRES_PASSWORD") && c.Postgres.DSN != "":
+			// подключение задано либо DSN, либо отдельными полями
+		default:
+			add(f.Env, "secret must not be empty")
+		}
+	}
+	if c.SwaggerEnabled {
+		add("APP_SWAGGER_ENABLED", "swagger UI must be disabled")
+	}
+	return p
+}
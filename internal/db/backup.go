@@ -0,0 +1,281 @@
+package db
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Формат резервной копии: gzip-поток из строки-заголовка backupHeader, затем для каждой таблицы
+// строки backupSection и данных COPY в текстовом формате, завершённых строкой `\.`. В данных COPY
+// обратная косая черта экранируется, поэтому строка `\.` не может встретиться внутри них.
+const (
+	backupFormat   = "k8s-hw-backup"
+	backupVersion  = 1
+	copyTerminator = "\\.\n"
+)
+
+// BackupTables — таблицы, которые попадают в резервную копию, в порядке записи и восстановления.
+// requests_rollup не сохраняется: CronJob пересчитает её по requests.
+var BackupTables = []string{"requests", "cron_runs"}
+
+type backupHeader struct {
+	Format        string    `json:"format"`
+	Version       int       `json:"version"`
+	SchemaVersion int64     `json:"schemaVersion"`
+	CreatedAt     time.Time `json:"createdAt"`
+}
+
+type backupSection struct {
+	Table   string   `json:"table"`
+	Columns []string `json:"columns"`
+}
+
+// BackupResult — итог резервного копирования или восстановления.
+type BackupResult struct {
+	SchemaVersion int64
+	Rows          map[string]int64
+}
+
+// SchemaVersion возвращает версию схемы из таблицы schema_migrations golang-migrate.
+// Схема в состоянии dirty (миграция упала на полпути) считается ошибкой.
+func (c *Client) SchemaVersion(ctx context.Context) (int64, error) {
+	return schemaVersion(ctx, c.pool)
+}
+
+type querier interface {
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+func schemaVersion(ctx context.Context, q querier) (int64, error) {
+	var (
+		version int64
+		dirty   bool
+	)
+	err := q.QueryRow(ctx, "SELECT version, dirty FROM schema_migrations LIMIT 1").Scan(&version, &dirty)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, errors.New("schema_migrations is empty: run migrations first")
+	}
+	if err != nil {
+		return 0, fmt.Errorf("schema version: %w", err)
+	}
+	if dirty {
+		return 0, fmt.Errorf("schema version %d is dirty: fix the failed migration first", version)
+	}
+	return version, nil
+}
+
+// Backup пишет в w сжатую копию BackupTables через COPY TO. Все таблицы читаются в одной
+// транзакции REPEATABLE READ, поэтому копия согласована, даже если приложение продолжает писать.
+func (c *Client) Backup(ctx context.Context, w io.Writer) (res BackupResult, err error) {
+	tx, err := c.pool.BeginTx(ctx, pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return res, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+	if res.SchemaVersion, err = schemaVersion(ctx, tx); err != nil {
+		return res, err
+	}
+
+	zw := gzip.NewWriter(w)
+	hdr, err := json.Marshal(backupHeader{Format: backupFormat, Version: backupVersion, SchemaVersion: res.SchemaVersion, CreatedAt: time.Now().UTC()})
+	if err != nil {
+		return res, err
+	}
+	if _, err := zw.Write(append(hdr, '\n')); err != nil {
+		return res, err
+	}
+	res.Rows = make(map[string]int64, len(BackupTables))
+	for _, table := range BackupTables {
+		cols, err := tableColumns(ctx, tx, table)
+		if err != nil {
+			return res, err
+		}
+		sec, err := json.Marshal(backupSection{Table: table, Columns: cols})
+		if err != nil {
+			return res, err
+		}
+		if _, err := zw.Write(append(sec, '\n')); err != nil {
+			return res, err
+		}
+		tag, err := tx.Conn().PgConn().CopyTo(ctx, zw, copyToSQL(table, cols))
+		if err != nil {
+			return res, fmt.Errorf("copy %s: %w", table, err)
+		}
+		res.Rows[table] = tag.RowsAffected()
+		if _, err := io.WriteString(zw, copyTerminator); err != nil {
+			return res, err
+		}
+	}
+	return res, zw.Close()
+}
+
+// Restore загружает копию, записанную Backup, через COPY FROM. Версия схемы копии должна
+// совпадать с версией БД, а таблицы BackupTables — быть пустыми. Всё выполняется в одной
+// транзакции: при ошибке БД остаётся пустой. Последовательности id сдвигаются за загруженные id.
+func (c *Client) Restore(ctx context.Context, r io.Reader) (res BackupResult, err error) {
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return res, fmt.Errorf("read backup: %w", err)
+	}
+	br := bufio.NewReader(zr)
+	var hdr backupHeader
+	if err := readJSONLine(br, &hdr); err != nil {
+		return res, fmt.Errorf("read backup header: %w", err)
+	}
+	if hdr.Format != backupFormat || hdr.Version != backupVersion {
+		return res, fmt.Errorf("unsupported backup format %q version %d", hdr.Format, hdr.Version)
+	}
+	res.SchemaVersion = hdr.SchemaVersion
+
+	tx, err := c.pool.Begin(ctx)
+	if err != nil {
+		return res, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+	version, err := schemaVersion(ctx, tx)
+	if err != nil {
+		return res, err
+	}
+	if version != hdr.SchemaVersion {
+		return res, fmt.Errorf("backup schema version %d does not match database schema version %d", hdr.SchemaVersion, version)
+	}
+	for _, table := range BackupTables {
+		var exists bool
+		if err := tx.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM "+pgx.Identifier{table}.Sanitize()+")").Scan(&exists); err != nil {
+			return res, err
+		}
+		if exists {
+			return res, fmt.Errorf("table %s is not empty: restore requires an empty database", table)
+		}
+	}
+
+	res.Rows = make(map[string]int64, len(BackupTables))
+	for {
+		var sec backupSection
+		err := readJSONLine(br, &sec)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return res, fmt.Errorf("read backup section: %w", err)
+		}
+		if !slices.Contains(BackupTables, sec.Table) || len(sec.Columns) == 0 {
+			return res, fmt.Errorf("unexpected table %q in backup", sec.Table)
+		}
+		if _, dup := res.Rows[sec.Table]; dup {
+			return res, fmt.Errorf("table %s appears twice in backup", sec.Table)
+		}
+		tag, err := tx.Conn().PgConn().CopyFrom(ctx, &copySection{br: br},
+			fmt.Sprintf("COPY %s (%s) FROM STDIN", pgx.Identifier{sec.Table}.Sanitize(), columnList(sec.Columns)))
+		if err != nil {
+			return res, fmt.Errorf("copy %s: %w", sec.Table, err)
+		}
+		res.Rows[sec.Table] = tag.RowsAffected()
+		if _, err := tx.Exec(ctx, "SELECT setval(pg_get_serial_sequence($1, 'id'), coalesce((SELECT max(id) FROM "+
+			pgx.Identifier{sec.Table}.Sanitize()+"), 0) + 1, false)", sec.Table); err != nil {
+			return res, fmt.Errorf("reset %s id sequence: %w", sec.Table, err)
+		}
+	}
+	if err := checkAllTables(res.Rows); err != nil {
+		return res, err
+	}
+	return res, tx.Commit(ctx)
+}
+
+// checkAllTables проверяет, что в копии были все BackupTables: копия без секции таблицы
+// (например, обрезанная по границе секции) не должна восстановиться частично.
+func checkAllTables(rows map[string]int64) error {
+	var missing []string
+	for _, t := range BackupTables {
+		if _, ok := rows[t]; !ok {
+			missing = append(missing, t)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("backup is incomplete: missing tables %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// tableColumns возвращает столбцы таблицы в порядке объявления.
+func tableColumns(ctx context.Context, tx pgx.Tx, table string) ([]string, error) {
+	rows, err := tx.Query(ctx, `SELECT column_name::text FROM information_schema.columns
+		WHERE table_schema = current_schema() AND table_name = $1 ORDER BY ordinal_position`, table)
+	if err != nil {
+		return nil, err
+	}
+	cols, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err == nil && len(cols) == 0 {
+		err = fmt.Errorf("table %s not found", table)
+	}
+	return cols, err
+}
+
+// copyToSQL возвращает COPY TO для таблицы через подзапрос: requests партиционирована
+// (миграция 0003), а COPY из партиционированной таблицы напрямую PostgreSQL не поддерживает.
+func copyToSQL(table string, cols []string) string {
+	return fmt.Sprintf("COPY (SELECT %s FROM %s ORDER BY id) TO STDOUT", columnList(cols), pgx.Identifier{table}.Sanitize())
+}
+
+func columnList(cols []string) string {
+	quoted := make([]string, len(cols))
+	for i, c := range cols {
+		quoted[i] = pgx.Identifier{c}.Sanitize()
+	}
+	return strings.Join(quoted, ", ")
+}
+
+// readJSONLine читает и разбирает одну строку JSON; io.EOF — если поток закончился ровно на границе строки.
+func readJSONLine(br *bufio.Reader, v any) error {
+	line, err := br.ReadBytes('\n')
+	if errors.Is(err, io.EOF) && len(line) == 0 {
+		return io.EOF
+	}
+	if errors.Is(err, io.EOF) {
+		return io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(line, v)
+}
+
+// copySection отдаёт данные COPY одной таблицы до строки-терминатора, не читая дальше.
+type copySection struct {
+	br   *bufio.Reader
+	buf  []byte
+	done bool
+}
+
+func (s *copySection) Read(p []byte) (int, error) {
+	for len(s.buf) == 0 {
+		if s.done {
+			return 0, io.EOF
+		}
+		line, err := s.br.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			return 0, io.ErrUnexpectedEOF // копия обрезана
+		}
+		if err != nil {
+			return 0, err
+		}
+		if string(line) == copyTerminator {
+			s.done = true
+			continue
+		}
+		s.buf = line
+	}
+	n := copy(p, s.buf)
+	s.buf = s.buf[n:]
+	return n, nil
+}
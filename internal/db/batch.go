@@ -0,0 +1,131 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"log"
+	"sync"
+	"time"
+
+	"k8s-hw/internal/lifecycle"
+	"k8s-hw/internal/watchdog"
+)
+
+// Ошибки Batcher.Enqueue.
+var (
+	ErrQueueFull = errors.New("insert queue is full")
+	ErrClosed    = errors.New("insert queue is closed")
+)
+
+// batchMetrics — счётчики write-behind вставок: queued, flushed, batches, dropped.
+var batchMetrics = expvar.NewMap("requests_batch")
+
+const flushAttempts = 3
+
+// Batcher — write-behind вставка в requests: Enqueue кладёт запись в очередь,
+// а Run сбрасывает её пачками по size записей или раз в interval. Переполненная очередь
+// отклоняет вставку (backpressure), а при остановке Run дописывает всё, что осталось.
+type Batcher struct {
+	queue    chan NewRequest
+	size     int
+	interval time.Duration
+	target   func() (RequestRepository, error)
+
+	mu     sync.RWMutex
+	closed bool
+}
+
+// NewBatcher создаёт очередь ёмкостью queueSize; target возвращает текущее хранилище
+// (пул может пересоздаваться, поэтому он запрашивается на каждый сброс).
+func NewBatcher(target func() (RequestRepository, error), size, queueSize int, interval time.Duration) *Batcher {
+	return &Batcher{
+		queue:    make(chan NewRequest, queueSize),
+		size:     size,
+		interval: interval,
+		target:   target,
+	}
+}
+
+// Enqueue ставит вставку в очередь, не дожидаясь записи в БД; r.CreatedAt должен быть задан.
+func (b *Batcher) Enqueue(r NewRequest) error {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	if b.closed {
+		return ErrClosed
+	}
+	select {
+	case b.queue <- r:
+		batchMetrics.Add("queued", 1)
+		return nil
+	default:
+		return ErrQueueFull
+	}
+}
+
+// Pending возвращает число записей в очереди.
+func (b *Batcher) Pending() int { return len(b.queue) }
+
+// Run сбрасывает очередь, пока ctx не отменён, затем закрывает её и дописывает остаток в
+// пределах дедлайна остановки lifecycle.Group (lifecycle.StopContext): main закрывает пул
+// только после Shutdown, поэтому остаток не пишется в закрытый пул. Отмечает heartbeat (watchdog.Beat).
+func (b *Batcher) Run(ctx context.Context) {
+	t := time.NewTicker(b.interval)
+	defer t.Stop()
+	buf := make([]NewRequest, 0, b.size)
+	for {
+		select {
+		case <-ctx.Done():
+			b.mu.Lock()
+			b.closed = true
+			b.mu.Unlock()
+			close(b.queue)
+			for r := range b.queue {
+				buf = append(buf, r)
+			}
+			fctx := lifecycle.StopContext(ctx)
+			for len(buf) > 0 {
+				n := min(len(buf), b.size)
+				b.flush(fctx, buf[:n])
+				buf = buf[n:]
+			}
+			return
+		case r := <-b.queue:
+			buf = append(buf, r)
+			if len(buf) < b.size {
+				continue
+			}
+		case <-t.C:
+			watchdog.Beat(ctx)
+			if len(buf) == 0 {
+				continue
+			}
+		}
+		b.flush(ctx, buf)
+		buf = buf[:0]
+	}
+}
+
+// flush пишет пачку, повторяя при ошибке; после flushAttempts попыток пачка теряется (в лог).
+func (b *Batcher) flush(ctx context.Context, batch []NewRequest) {
+	var err error
+	for attempt := 1; attempt <= flushAttempts; attempt++ {
+		var repo RequestRepository
+		if repo, err = b.target(); err == nil {
+			if err = repo.InsertRequests(ctx, batch); err == nil {
+				batchMetrics.Add("flushed", int64(len(batch)))
+				batchMetrics.Add("batches", 1)
+				return
+			}
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		select {
+		case <-ctx.Done():
+		case <-time.After(time.Duration(attempt) * 500 * time.Millisecond):
+		}
+	}
+	batchMetrics.Add("dropped", int64(len(batch)))
+	log.Printf("ERROR: dropped %d queued inserts into requests: %v", len(batch), err)
+}
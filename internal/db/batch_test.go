@@ -0,0 +1,46 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestBatcherFlushesOnShutdown(t *testing.T) {
+	mem := NewMemory()
+	b := NewBatcher(func() (RequestRepository, error) { return mem, nil }, 3, 4, time.Hour)
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := range 4 {
+		if err := b.Enqueue(NewRequest{CreatedAt: base.Add(time.Duration(i) * time.Second)}); err != nil {
+			t.Fatalf("enqueue %d: %v", i, err)
+		}
+	}
+	if err := b.Enqueue(NewRequest{CreatedAt: base}); !errors.Is(err, ErrQueueFull) {
+		t.Fatalf("expected backpressure on full queue, got %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() { b.Run(ctx); close(done) }()
+	deadline := time.Now().Add(5 * time.Second)
+	for { // первая пачка (3 записи) сбрасывается по размеру, не дожидаясь интервала
+		if got, _ := mem.ListRequests(ctx, 10); len(got) >= 3 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("full batch was not flushed")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	cancel()
+	<-done
+
+	got, _ := mem.ListRequests(context.Background(), 10)
+	if len(got) != 4 || !got[0].CreatedAt.Equal(base.Add(3*time.Second)) {
+		t.Fatalf("expected all 4 queued inserts flushed with their timestamps, got %+v", got)
+	}
+	if err := b.Enqueue(NewRequest{CreatedAt: base}); !errors.Is(err, ErrClosed) {
+		t.Fatalf("expected ErrClosed after shutdown, got %v", err)
+	}
+}
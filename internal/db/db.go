@@ -3,57 +3,219 @@ package db
 import (
 	"context"
 	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync/atomic"
 	"time"
 
 	"k8s-hw/internal/config"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
-// Client обёртка над пулом соединений.
+// Client обёртка над пулом соединений к primary и (необязательно) к read-репликам.
+// Запись всегда идёт на primary, запросы только на чтение — на здоровые реплики (см. read).
 type Client struct {
-	pool *pgxpool.Pool
+	pool      *pgxpool.Pool
+	replicas  []*replica
+	next      atomic.Uint32
+	lastReset atomic.Int64 // время последнего сброса пула после failover, UnixNano
 }
 
-// New создаёт пул подключений к Postgres на основе конфигурации.
-func New(ctx context.Context, pc config.Postgres) (*Client, error) {
-	if pc.Host == "" || pc.User == "" || pc.DB == "" {
-		return nil, fmt.Errorf("postgres config incomplete (host/user/db required)")
+// Request — запись таблицы requests.
+type Request struct {
+	ID        int64     `json:"id"`
+	CreatedAt time.Time `json:"createdAt"`
+	Pod       string    `json:"pod,omitempty"`
+	Path      string    `json:"path,omitempty"`
+}
+
+// NewRequest — данные новой записи requests; нулевой CreatedAt означает now() сервера БД.
+type NewRequest struct {
+	CreatedAt time.Time
+	Pod       string
+	Path      string
+}
+
+// createdAtArg — нулевое время передаётся как NULL, чтобы сработал coalesce(…, now()).
+func (r NewRequest) createdAtArg() *time.Time {
+	if r.CreatedAt.IsZero() {
+		return nil
 	}
-	connStr := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=disable pool_max_conns=5", pc.Host, pc.Port, pc.User, pc.Pass, pc.DB)
-	cfg, err := pgxpool.ParseConfig(connStr)
+	return &r.CreatedAt
+}
+
+// New создаёт пул подключений к Postgres на основе конфигурации: DSN (или host/port/user/
+// password/db), режим TLS, размер пула, таймауты и параметры сессии.
+func New(ctx context.Context, pc config.Postgres) (*Client, error) {
+	cfg, err := PoolConfig(pc)
 	if err != nil {
-		return nil, fmt.Errorf("parse config: %w", err)
+		return nil, err
 	}
-	cfg.MaxConnIdleTime = 2 * time.Minute
-	cfg.MaxConnLifetime = 30 * time.Minute
 	pool, err := pgxpool.NewWithConfig(ctx, cfg)
 	if err != nil {
 		return nil, fmt.Errorf("create pool: %w", err)
 	}
-	return &Client{pool: pool}, nil
+	replicas, err := newReplicas(ctx, cfg, pc)
+	if err != nil {
+		pool.Close()
+		return nil, err
+	}
+	return &Client{pool: pool, replicas: replicas}, nil
+}
+
+// PoolConfig собирает конфигурацию пула pgx из config.Postgres.
+func PoolConfig(pc config.Postgres) (*pgxpool.Config, error) {
+	if !pc.Enabled() || pc.DSN == "" && pc.Host == "" {
+		return nil, fmt.Errorf("postgres config incomplete (dsn or host/user/db required)")
+	}
+	connStr, err := connString(pc)
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := pgxpool.ParseConfig(connStr)
+	if err != nil {
+		return nil, fmt.Errorf("parse config: %w", err)
+	}
+	// незаданный размер пула не перекрывает pool_max_conns / pool_min_conns из DSN
+	if n := pc.EffectiveMaxConns(); n > 0 {
+		cfg.MaxConns = int32(n)
+	}
+	if pc.MinConns > 0 {
+		cfg.MinConns = int32(pc.MinConns)
+	}
+	if d := pc.MaxConnIdle(); d > 0 {
+		cfg.MaxConnIdleTime = d
+	}
+	if d := pc.MaxConnLifetime(); d > 0 {
+		cfg.MaxConnLifetime = d
+	}
+	if d := pc.HealthCheckPeriod(); d > 0 {
+		cfg.HealthCheckPeriod = d
+	}
+	if d := pc.ConnectTimeout(); d > 0 {
+		cfg.ConnConfig.ConnectTimeout = d
+	}
+	params := cfg.ConnConfig.RuntimeParams
+	if d := pc.StatementTimeout(); d > 0 {
+		params["statement_timeout"] = strconv.FormatInt(d.Milliseconds(), 10)
+	}
+	if pc.ApplicationName != "" {
+		params["application_name"] = pc.ApplicationName
+	}
+	if pc.SearchPath != "" {
+		params["search_path"] = pc.SearchPath
+	}
+	return cfg, nil
+}
+
+// connString возвращает строку подключения: DSN или key=value из отдельных полей (значения
+// экранируются, пароль может содержать пробелы и кавычки) с подставленными явно заданными
+// параметрами TLS; незаданные остаются такими, как в DSN.
+func connString(pc config.Postgres) (string, error) {
+	var tls [][2]string
+	for _, p := range [][2]string{{"sslmode", pc.EffectiveSSLMode()}, {"sslrootcert", pc.SSLRootCert}, {"sslcert", pc.SSLCert}, {"sslkey", pc.SSLKey}} {
+		if p[1] != "" {
+			tls = append(tls, p)
+		}
+	}
+	if strings.HasPrefix(pc.DSN, "postgres://") || strings.HasPrefix(pc.DSN, "postgresql://") {
+		u, err := url.Parse(pc.DSN)
+		if err != nil {
+			return "", fmt.Errorf("parse dsn: invalid URL") // без деталей: в URL может быть пароль
+		}
+		q := u.Query()
+		for _, p := range tls {
+			q.Set(p[0], p[1])
+		}
+		u.RawQuery = q.Encode()
+		return u.String(), nil
+	}
+	conn := pc.DSN
+	if conn == "" {
+		conn = fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s",
+			quote(pc.Host), pc.Port, quote(pc.User), quote(pc.Pass), quote(pc.DB))
+	}
+	// в формате key=value повторный ключ переопределяет предыдущий
+	for _, p := range tls {
+		conn += " " + p[0] + "=" + quote(p[1])
+	}
+	return conn, nil
+}
+
+func quote(v string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(v) + "'"
 }
 
 // InsertRequest вставляет новую запись и возвращает id и timestamp.
-func (c *Client) InsertRequest(ctx context.Context) (id int64, createdAt time.Time, err error) {
-	row := c.pool.QueryRow(ctx, "INSERT INTO requests DEFAULT VALUES RETURNING id, created_at")
-	if err = row.Scan(&id, &createdAt); err != nil {
+func (c *Client) InsertRequest(ctx context.Context, r NewRequest) (id int64, createdAt time.Time, err error) {
+	err = c.write(ctx, false, func() error {
+		return c.pool.QueryRow(ctx, "INSERT INTO requests (created_at, pod_name, path) VALUES (coalesce($1, now()), $2, $3) RETURNING id, created_at",
+			r.createdAtArg(), r.Pod, r.Path).Scan(&id, &createdAt)
+	})
+	if err != nil {
 		return 0, time.Time{}, err
 	}
 	return
 }
 
+// InsertRequests вставляет пачку записей через COPY. Сервер, отклонивший COPY после failover
+// (read-only), его не выполнил — поэтому повтор безопасен (см. write).
+func (c *Client) InsertRequests(ctx context.Context, rs []NewRequest) error {
+	return c.write(ctx, false, func() error {
+		_, err := c.pool.CopyFrom(ctx, pgx.Identifier{"requests"}, []string{"created_at", "pod_name", "path"},
+			pgx.CopyFromSlice(len(rs), func(i int) ([]any, error) { return []any{rs[i].CreatedAt, rs[i].Pod, rs[i].Path}, nil }))
+		return err
+	})
+}
+
 // InsertCronRun вставляет запись о выполнении cron и возвращает id и executed_at.
 func (c *Client) InsertCronRun(ctx context.Context) (id int64, executedAt time.Time, err error) {
-	row := c.pool.QueryRow(ctx, "INSERT INTO cron_runs DEFAULT VALUES RETURNING id, executed_at")
-	if err = row.Scan(&id, &executedAt); err != nil {
+	err = c.write(ctx, false, func() error {
+		return c.pool.QueryRow(ctx, "INSERT INTO cron_runs DEFAULT VALUES RETURNING id, executed_at").Scan(&id, &executedAt)
+	})
+	if err != nil {
 		return 0, time.Time{}, err
 	}
 	return
 }
 
-// Ping проверяет доступность БД.
+// ListRequests возвращает последние limit записей requests (с реплики, если она есть).
+func (c *Client) ListRequests(ctx context.Context, limit int) ([]Request, error) {
+	var out []Request
+	err := c.read(ctx, func(p *pgxpool.Pool) error {
+		rows, err := p.Query(ctx, "SELECT id, created_at, pod_name, path FROM requests ORDER BY id DESC LIMIT $1", limit)
+		if err != nil {
+			return err
+		}
+		out, err = pgx.CollectRows(rows, pgx.RowToStructByPos[Request])
+		return err
+	})
+	return out, err
+}
+
+// LastCronRun возвращает время последнего выполнения cron (нулевое, если запусков не было).
+func (c *Client) LastCronRun(ctx context.Context) (time.Time, error) {
+	var ts *time.Time
+	err := c.read(ctx, func(p *pgxpool.Pool) error {
+		return p.QueryRow(ctx, "SELECT max(executed_at) FROM cron_runs").Scan(&ts)
+	})
+	if err != nil {
+		return time.Time{}, err
+	}
+	if ts == nil {
+		return time.Time{}, nil
+	}
+	return *ts, nil
+}
+
+// Ping проверяет доступность primary.
 func (c *Client) Ping(ctx context.Context) error { return c.pool.Ping(ctx) }
 
-// Close закрывает пул.
-func (c *Client) Close() { c.pool.Close() }
+// Close закрывает пулы primary и реплик.
+func (c *Client) Close() {
+	c.pool.Close()
+	closeReplicas(c.replicas)
+}
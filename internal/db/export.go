@@ -0,0 +1,123 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// CronRun — запись таблицы cron_runs.
+type CronRun struct {
+	ID         int64     `json:"id"`
+	ExecutedAt time.Time `json:"executedAt"`
+}
+
+// TimeRange — полуинтервал [From, To); нулевая граница не ограничивает.
+type TimeRange struct {
+	From time.Time
+	To   time.Time
+}
+
+func (tr TimeRange) contains(t time.Time) bool {
+	return (tr.From.IsZero() || !t.Before(tr.From)) && (tr.To.IsZero() || t.Before(tr.To))
+}
+
+// where возвращает условие по столбцу col и аргументы к нему.
+func (tr TimeRange) where(col string) (string, []any) {
+	cond, args := "true", []any{}
+	if !tr.From.IsZero() {
+		args = append(args, tr.From)
+		cond += fmt.Sprintf(" AND %s >= $%d", col, len(args))
+	}
+	if !tr.To.IsZero() {
+		args = append(args, tr.To)
+		cond += fmt.Sprintf(" AND %s < $%d", col, len(args))
+	}
+	return cond, args
+}
+
+// ExportRequests передаёт fn записи requests из tr в порядке времени, читая их из БД построчно:
+// результат не накапливается в памяти. Ошибка fn прерывает выгрузку.
+func (c *Client) ExportRequests(ctx context.Context, tr TimeRange, fn func(Request) error) error {
+	cond, args := tr.where("created_at")
+	return c.export(ctx, "SELECT id, created_at, pod_name, path FROM requests WHERE "+cond+" ORDER BY created_at, id", args,
+		func(rows pgx.Rows) error {
+			var r Request
+			if err := rows.Scan(&r.ID, &r.CreatedAt, &r.Pod, &r.Path); err != nil {
+				return err
+			}
+			return fn(r)
+		})
+}
+
+// ExportCronRuns передаёт fn записи cron_runs из tr, как ExportRequests.
+func (c *Client) ExportCronRuns(ctx context.Context, tr TimeRange, fn func(CronRun) error) error {
+	cond, args := tr.where("executed_at")
+	return c.export(ctx, "SELECT id, executed_at FROM cron_runs WHERE "+cond+" ORDER BY executed_at, id", args,
+		func(rows pgx.Rows) error {
+			var r CronRun
+			if err := rows.Scan(&r.ID, &r.ExecutedAt); err != nil {
+				return err
+			}
+			return fn(r)
+		})
+}
+
+// export читает с реплики (или primary) без повтора при ошибке: часть строк уже могла уйти клиенту.
+func (c *Client) export(ctx context.Context, sql string, args []any, row func(pgx.Rows) error) error {
+	pool := c.pool
+	if r := c.reader(); r != nil {
+		pool = r.pool
+	}
+	rows, err := pool.Query(ctx, sql, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		if err := row(rows); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// ExportRequests передаёт fn копии записей из tr в порядке времени.
+func (m *Memory) ExportRequests(_ context.Context, tr TimeRange, fn func(Request) error) error {
+	m.mu.Lock()
+	var out []Request
+	for _, r := range m.requests {
+		if tr.contains(r.CreatedAt) {
+			out = append(out, r)
+		}
+	}
+	m.mu.Unlock()
+	slices.SortStableFunc(out, func(a, b Request) int { return a.CreatedAt.Compare(b.CreatedAt) })
+	for _, r := range out {
+		if err := fn(r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ExportCronRuns передаёт fn запуски cron из tr.
+func (m *Memory) ExportCronRuns(_ context.Context, tr TimeRange, fn func(CronRun) error) error {
+	m.mu.Lock()
+	var out []CronRun
+	for i, ts := range m.cronRuns {
+		if tr.contains(ts) {
+			out = append(out, CronRun{ID: int64(i) + 1, ExecutedAt: ts})
+		}
+	}
+	m.mu.Unlock()
+	for _, r := range out {
+		if err := fn(r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"io"
+	"log"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Метрики failover, публикуются через expvar (/admin/metrics): failovers, failover_retries, last_failover.
+var (
+	metrics      = expvar.NewMap("db")
+	lastFailover = new(expvar.String)
+)
+
+func init() { metrics.Set("last_failover", lastFailover) }
+
+// resetDebounce — повторные failover-ошибки от уже открытых соединений в течение этого
+// времени не сбрасывают пул заново.
+const resetDebounce = time.Second
+
+// failoverCodes — SQLSTATE, после которых соединения пула смотрят не на текущий primary.
+var failoverCodes = map[string]bool{
+	"25006": true, // read_only_sql_transaction: бывший primary стал репликой
+	"57P01": true, // admin_shutdown
+	"57P02": true, // crash_shutdown
+	"57P03": true, // cannot_connect_now: сервер в recovery
+}
+
+// IsFailover сообщает, что ошибка вызвана сменой или перезапуском primary: SQLSTATE выше или
+// класс 08 (connection exception), обрыв соединения (сетевая ошибка, EOF) или отказ до отправки
+// запроса (pgconn.SafeToRetry). Отмена и истечение ctx failover не считаются.
+func IsFailover(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return failoverCodes[pgErr.Code] || strings.HasPrefix(pgErr.Code, "08")
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) || pgconn.SafeToRetry(err)
+}
+
+// notExecuted сообщает, что сервер гарантированно не выполнил запрос: его можно повторить,
+// даже если операция не идемпотентна.
+func notExecuted(err error) bool {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.Code == "25006" || pgErr.Code == "57P03"
+	}
+	return pgconn.SafeToRetry(err)
+}
+
+// write выполняет запрос на primary. При failover-ошибке пул сбрасывается (новые соединения
+// попадут на новый primary), а запрос повторяется один раз, если он идемпотентен или сервер
+// его точно не выполнил.
+func (c *Client) write(ctx context.Context, idempotent bool, fn func() error) error {
+	err := fn()
+	if err == nil || !IsFailover(err) {
+		return err
+	}
+	c.resetOnFailover(err)
+	if ctx.Err() != nil || !idempotent && !notExecuted(err) {
+		return err
+	}
+	metrics.Add("failover_retries", 1)
+	return fn()
+}
+
+// resetOnFailover закрывает все соединения пула primary.
+func (c *Client) resetOnFailover(err error) {
+	now := time.Now().UnixNano()
+	last := c.lastReset.Load()
+	if now-last < int64(resetDebounce) || !c.lastReset.CompareAndSwap(last, now) {
+		return
+	}
+	metrics.Add("failovers", 1)
+	lastFailover.Set(time.Now().UTC().Format(time.RFC3339))
+	log.Printf("db: primary failover detected (%v), resetting pool", err)
+	c.pool.Reset()
+}
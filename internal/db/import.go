@@ -0,0 +1,104 @@
+package db
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ImportResult — итог массовой вставки: сколько записей вставлено и диапазон их id.
+type ImportResult struct {
+	Count   int64 `json:"count"`
+	FirstID int64 `json:"firstId,omitempty"`
+	LastID  int64 `json:"lastId,omitempty"`
+}
+
+// RequestSource отдаёт записи для массовой вставки по одной; ok=false — записи закончились.
+type RequestSource func() (r NewRequest, ok bool, err error)
+
+// copySource адаптирует RequestSource к pgx.CopyFromSource.
+type copySource struct {
+	next RequestSource
+	cur  NewRequest
+	err  error
+}
+
+func (s *copySource) Next() bool {
+	r, ok, err := s.next()
+	if err != nil {
+		s.err = err
+		return false
+	}
+	s.cur = r
+	return ok
+}
+
+func (s *copySource) Values() ([]any, error) {
+	return []any{s.cur.CreatedAt, s.cur.Pod, s.cur.Path}, nil
+}
+func (s *copySource) Err() error { return s.err }
+
+// ImportRequests потоково вставляет записи из src через COPY: сначала во временную таблицу,
+// затем одним INSERT … RETURNING в requests, чтобы получить диапазон id. Всё в одной транзакции:
+// ошибка в данных посередине не оставляет частичной вставки, а записи задним числом сразу
+// попадают в requests_rollup (rollupImported). Источник читается один раз,
+// поэтому после failover запрос не повторяется (пул лишь сбрасывается).
+func (c *Client) ImportRequests(ctx context.Context, src RequestSource) (res ImportResult, err error) {
+	defer func() {
+		if IsFailover(err) {
+			c.resetOnFailover(err)
+		}
+	}()
+	tx, err := c.pool.Begin(ctx)
+	if err != nil {
+		return res, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }() // после Commit ничего не делает
+
+	if _, err = tx.Exec(ctx, "CREATE TEMP TABLE requests_import (created_at timestamptz NOT NULL, pod_name text NOT NULL, path text NOT NULL) ON COMMIT DROP"); err != nil {
+		return res, fmt.Errorf("create staging table: %w", err)
+	}
+	if _, err = tx.CopyFrom(ctx, pgx.Identifier{"requests_import"}, []string{"created_at", "pod_name", "path"}, &copySource{next: src}); err != nil {
+		return res, fmt.Errorf("copy: %w", err)
+	}
+	err = tx.QueryRow(ctx, `WITH ins AS (
+		INSERT INTO requests (created_at, pod_name, path) SELECT created_at, pod_name, path FROM requests_import RETURNING id
+	) SELECT count(*), coalesce(min(id), 0), coalesce(max(id), 0) FROM ins`).Scan(&res.Count, &res.FirstID, &res.LastID)
+	if err != nil {
+		return ImportResult{}, fmt.Errorf("insert: %w", err)
+	}
+	if err = rollupImported(ctx, tx); err != nil {
+		return ImportResult{}, fmt.Errorf("update stats rollup: %w", err)
+	}
+	if err = tx.Commit(ctx); err != nil {
+		return ImportResult{}, err
+	}
+	return res, nil
+}
+
+// ImportRequests вставляет записи из src; при ошибке источника ничего не вставляется.
+func (m *Memory) ImportRequests(_ context.Context, src RequestSource) (ImportResult, error) {
+	var batch []NewRequest
+	for {
+		r, ok, err := src()
+		if err != nil {
+			return ImportResult{}, err
+		}
+		if !ok {
+			break
+		}
+		batch = append(batch, r)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	res := ImportResult{Count: int64(len(batch))}
+	for _, r := range batch {
+		id := m.add(r).ID
+		if res.FirstID == 0 {
+			res.FirstID = id
+		}
+		res.LastID = id
+	}
+	return res, nil
+}
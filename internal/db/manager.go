@@ -0,0 +1,281 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand/v2"
+	"reflect"
+	"sync"
+	"time"
+
+	"k8s-hw/internal/config"
+	"k8s-hw/internal/watchdog"
+)
+
+// State — состояние подключения Manager.
+type State string
+
+const (
+	StateDisabled   State = "disabled"   // Postgres не сконфигурирован
+	StateConnecting State = "connecting" // идёт попытка подключения
+	StateReady      State = "ready"      // пул создан, последний ping успешен
+	StateFailed     State = "failed"     // последняя попытка подключения или ping провалились
+)
+
+const (
+	reconnectMinBackoff = 500 * time.Millisecond
+	// ReconnectMaxBackoff — верхняя граница паузы между попытками подключения.
+	ReconnectMaxBackoff = 30 * time.Second
+	connectTimeout      = 5 * time.Second // если APP_POSTGRES_CONNECT_TIMEOUT_SECONDS не задан
+	idleTick            = 5 * time.Second // как часто цикл просыпается без дела (heartbeat watchdog)
+)
+
+// ErrNotConnected возвращается Client, пока пул не создан.
+var ErrNotConnected = errors.New("db not connected")
+
+// Manager владеет пулом Postgres: подключается в фоне с экспоненциальной паузой между
+// попытками, пересоздаёт пул при смене конфигурации и отдаёт своё состояние.
+// Безопасен для одновременного использования.
+type Manager struct {
+	mu      sync.Mutex
+	cfg     config.Postgres
+	gen     uint64 // увеличивается при каждой смене конфигурации
+	client  *Client
+	reload  bool // пересоздать пул, не закрывая текущий до успешного подключения
+	state   State
+	lastErr error
+	wake    chan struct{}
+
+	connect func(context.Context, config.Postgres) (*Client, error)
+}
+
+// NewManager создаёт менеджер; подключение начинается в Run.
+func NewManager(pc config.Postgres) *Manager {
+	m := &Manager{cfg: pc, wake: make(chan struct{}, 1), connect: connectAndPing}
+	m.state = m.initialState()
+	return m
+}
+
+func (m *Manager) initialState() State {
+	if !m.cfg.Enabled() {
+		return StateDisabled
+	}
+	return StateConnecting
+}
+
+// connectAndPing создаёт пул и проверяет, что БД действительно отвечает (pgxpool подключается лениво).
+func connectAndPing(ctx context.Context, pc config.Postgres) (*Client, error) {
+	c, err := New(ctx, pc)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.Ping(ctx); err != nil {
+		c.Close()
+		return nil, fmt.Errorf("ping: %w", err)
+	}
+	_ = c.CheckReplicas(ctx) // недоступные реплики не мешают работе: чтения пойдут на primary
+	return c, nil
+}
+
+// Enabled сообщает, сконфигурирован ли Postgres.
+func (m *Manager) Enabled() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.cfg.Enabled()
+}
+
+// State возвращает текущее состояние и последнюю ошибку (nil в состоянии ready).
+func (m *Manager) State() (State, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.state, m.lastErr
+}
+
+// Client возвращает текущий пул или ErrNotConnected, пока он не создан.
+// Пул остаётся доступным и в состоянии failed после потери связи: pgx сам переподключает соединения.
+func (m *Manager) Client() (*Client, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.client == nil {
+		if m.lastErr != nil {
+			return nil, fmt.Errorf("%w (%s): %v", ErrNotConnected, m.state, m.lastErr)
+		}
+		return nil, fmt.Errorf("%w (%s)", ErrNotConnected, m.state)
+	}
+	return m.client, nil
+}
+
+// Ping проверяет БД через текущий пул и обновляет состояние ready/failed.
+func (m *Manager) Ping(ctx context.Context) error {
+	c, err := m.Client()
+	if err != nil {
+		return err
+	}
+	err = c.Ping(ctx)
+	m.mu.Lock()
+	if m.client == c {
+		m.setState(StateReady, err)
+	}
+	m.mu.Unlock()
+	return err
+}
+
+// CheckReplicas проверяет read-реплики текущего пула (см. Client.CheckReplicas).
+func (m *Manager) CheckReplicas(ctx context.Context) error {
+	c, err := m.Client()
+	if err != nil {
+		return nil // недоступность БД отражает проверка db
+	}
+	return c.CheckReplicas(ctx)
+}
+
+// Config возвращает текущие параметры Postgres (с учётом Reconfigure).
+func (m *Manager) Config() config.Postgres {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.cfg
+}
+
+// Reconfigure применяет новые параметры Postgres: старый пул закрывается, новый создаётся
+// циклом Run. Возвращает false, если параметры не изменились.
+func (m *Manager) Reconfigure(pc config.Postgres) bool {
+	m.mu.Lock()
+	if reflect.DeepEqual(pc, m.cfg) {
+		m.mu.Unlock()
+		return false
+	}
+	m.cfg = pc
+	m.gen++
+	old := m.client
+	m.client = nil
+	m.reload = false
+	m.lastErr = nil
+	m.state = m.initialState()
+	m.mu.Unlock()
+
+	if old != nil {
+		old.Close() // дожидается возврата занятых соединений
+	}
+	m.notify()
+	return true
+}
+
+// Reload пересоздаёт пул с той же конфигурацией (например, обновились сертификаты TLS:
+// pgx читает их при создании пула). Текущий пул работает, пока новый не подключится;
+// если подключиться не удалось, он остаётся, а состояние переходит в failed.
+func (m *Manager) Reload() {
+	m.mu.Lock()
+	m.reload = m.client != nil
+	m.mu.Unlock()
+	m.notify()
+}
+
+// Close закрывает пул (если он был создан) и сообщает, был ли он.
+func (m *Manager) Close() bool {
+	m.mu.Lock()
+	c := m.client
+	m.client = nil
+	m.mu.Unlock()
+	if c == nil {
+		return false
+	}
+	c.Close()
+	return true
+}
+
+func (m *Manager) notify() {
+	select {
+	case m.wake <- struct{}{}:
+	default:
+	}
+}
+
+// setState меняет состояние (ошибка переводит в failed) и логирует переходы. Вызывается под m.mu.
+func (m *Manager) setState(ok State, err error) {
+	next := ok
+	if err != nil {
+		next = StateFailed
+	}
+	if next != m.state {
+		if err != nil {
+			log.Printf("db: %s -> %s: %v", m.state, next, err)
+		} else {
+			log.Printf("db: %s -> %s", m.state, next)
+		}
+	}
+	m.state, m.lastErr = next, err
+}
+
+// Run подключается к БД, пока пул не создан, с экспоненциальной паузой между попытками
+// (от 500ms до ReconnectMaxBackoff, с джиттером). Отмечает heartbeat (watchdog.Beat).
+// Блокируется до отмены ctx.
+func (m *Manager) Run(ctx context.Context) {
+	backoff := reconnectMinBackoff
+	for {
+		watchdog.Beat(ctx)
+		wait := idleTick
+		m.mu.Lock()
+		pc, gen := m.cfg, m.gen
+		reload := m.reload && m.client != nil
+		need := pc.Enabled() && (m.client == nil || reload)
+		if need && !reload {
+			m.setState(StateConnecting, nil)
+		}
+		m.mu.Unlock()
+
+		if need {
+			timeout := connectTimeout
+			if d := pc.ConnectTimeout(); d > 0 {
+				timeout = d + time.Second // запас на ping после подключения
+			}
+			cctx, cancel := context.WithTimeout(ctx, timeout)
+			c, err := m.connect(cctx, pc)
+			cancel()
+			if err != nil && ctx.Err() != nil {
+				return
+			}
+			var old *Client
+			m.mu.Lock()
+			stale := gen != m.gen // конфигурация сменилась во время подключения
+			if !stale {
+				if err == nil {
+					old, m.client, m.reload = m.client, c, false
+				}
+				m.setState(StateReady, err)
+			}
+			m.mu.Unlock()
+			if old != nil {
+				old.Close() // дожидается возврата занятых соединений
+			}
+			switch {
+			case stale:
+				if c != nil {
+					c.Close()
+				}
+				wait = 0
+			case err != nil:
+				wait = backoff/2 + rand.N(backoff/2+1)
+				backoff = min(backoff*2, ReconnectMaxBackoff)
+			case old != nil:
+				log.Println("db: pool recreated")
+				backoff = reconnectMinBackoff
+			default:
+				log.Printf("db: connected host=%s db=%s", pc.Host, pc.DB)
+				backoff = reconnectMinBackoff
+			}
+		}
+
+		t := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return
+		case <-m.wake:
+			backoff = reconnectMinBackoff
+		case <-t.C:
+		}
+		t.Stop()
+	}
+}
@@ -0,0 +1,100 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"k8s-hw/internal/config"
+)
+
+func TestManagerReconnectsWithBackoff(t *testing.T) {
+	m := NewManager(config.Postgres{Host: "db", User: "u", DB: "app"})
+	var attempts atomic.Int32
+	m.connect = func(context.Context, config.Postgres) (*Client, error) {
+		if attempts.Add(1) < 3 {
+			return nil, errors.New("connection refused")
+		}
+		return &Client{}, nil
+	}
+	if st, _ := m.State(); st != StateConnecting {
+		t.Fatalf("initial state = %s, want connecting", st)
+	}
+	if _, err := m.Client(); !errors.Is(err, ErrNotConnected) {
+		t.Fatalf("expected ErrNotConnected before connect, got %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() { m.Run(ctx); close(done) }()
+	defer func() { cancel(); <-done }()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		st, err := m.State()
+		if st == StateReady {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("not ready after %d attempts: %s %v", attempts.Load(), st, err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if attempts.Load() != 3 {
+		t.Fatalf("attempts = %d, want 3", attempts.Load())
+	}
+	if c, err := m.Client(); err != nil || c == nil {
+		t.Fatalf("Client() = %v, %v", c, err)
+	}
+}
+
+func TestManagerDisabled(t *testing.T) {
+	m := NewManager(config.Postgres{Host: "db"})
+	if st, _ := m.State(); st != StateDisabled || m.Enabled() {
+		t.Fatalf("state = %s, want disabled", st)
+	}
+	if m.Reconfigure(config.Postgres{Host: "db"}) {
+		t.Fatal("Reconfigure with the same config reported a change")
+	}
+}
+
+func TestManagerReloadKeepsPoolOnFailure(t *testing.T) {
+	m := NewManager(config.Postgres{Host: "db", User: "u", DB: "app"})
+	first := &Client{}
+	var fail atomic.Bool
+	m.connect = func(context.Context, config.Postgres) (*Client, error) {
+		if fail.Load() {
+			return nil, errors.New("tls: certificate expired")
+		}
+		return first, nil
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() { m.Run(ctx); close(done) }()
+	defer func() { cancel(); <-done }()
+
+	waitState := func(want State) {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for {
+			if st, _ := m.State(); st == want {
+				return
+			}
+			if time.Now().After(deadline) {
+				st, err := m.State()
+				t.Fatalf("state = %s (%v), want %s", st, err, want)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+	waitState(StateReady)
+
+	fail.Store(true)
+	m.Reload()
+	waitState(StateFailed)
+	if c, err := m.Client(); err != nil || c != first {
+		t.Fatalf("failed reload must keep the current pool, got %v, %v", c, err)
+	}
+}
@@ -0,0 +1,78 @@
+package db
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Memory — хранилище в памяти процесса: используется, когда Postgres не сконфигурирован
+// (локальный запуск), и в тестах. Данные теряются при перезапуске.
+type Memory struct {
+	mu       sync.Mutex
+	requests []Request
+	cronRuns []time.Time
+	now      func() time.Time
+}
+
+// NewMemory создаёт пустое хранилище.
+func NewMemory() *Memory { return &Memory{now: time.Now} }
+
+// InsertRequest добавляет запись; id растут с 1, как у bigserial.
+func (m *Memory) InsertRequest(_ context.Context, r NewRequest) (int64, time.Time, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	rec := m.add(r)
+	return rec.ID, rec.CreatedAt, nil
+}
+
+// InsertRequests добавляет пачку записей.
+func (m *Memory) InsertRequests(_ context.Context, rs []NewRequest) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, r := range rs {
+		m.add(r)
+	}
+	return nil
+}
+
+// add добавляет запись под m.mu.
+func (m *Memory) add(r NewRequest) Request {
+	ts := r.CreatedAt
+	if ts.IsZero() {
+		ts = m.now()
+	}
+	rec := Request{ID: int64(len(m.requests)) + 1, CreatedAt: ts.UTC(), Pod: r.Pod, Path: r.Path}
+	m.requests = append(m.requests, rec)
+	return rec
+}
+
+// ListRequests возвращает последние limit записей, новые первыми.
+func (m *Memory) ListRequests(_ context.Context, limit int) ([]Request, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := make([]Request, 0, min(limit, len(m.requests)))
+	for i := len(m.requests) - 1; i >= 0 && len(out) < limit; i-- {
+		out = append(out, m.requests[i])
+	}
+	return out, nil
+}
+
+// InsertCronRun добавляет отметку запуска cron.
+func (m *Memory) InsertCronRun(context.Context) (int64, time.Time, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	ts := m.now().UTC()
+	m.cronRuns = append(m.cronRuns, ts)
+	return int64(len(m.cronRuns)), ts, nil
+}
+
+// LastCronRun возвращает время последнего запуска cron.
+func (m *Memory) LastCronRun(context.Context) (time.Time, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.cronRuns) == 0 {
+		return time.Time{}, nil
+	}
+	return m.cronRuns[len(m.cronRuns)-1], nil
+}
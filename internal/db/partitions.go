@@ -0,0 +1,173 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Партиции requests — месячные, в UTC: requests_YYYY_MM (см. migrations/0003_partition_requests_table.up.sql).
+const (
+	partitionPrefix = "requests_"
+	partitionLayout = "2006_01"
+)
+
+// PartitionResult — итог обслуживания партиций.
+type PartitionResult struct {
+	Created  []string
+	Dropped  []string
+	Archives []string
+}
+
+// PartitionName возвращает имя месячной партиции requests, в которую попадает t.
+func PartitionName(t time.Time) string {
+	return partitionPrefix + t.UTC().Format(partitionLayout)
+}
+
+// partitionMonth разбирает имя партиции; ok=false для requests_default и чужих таблиц.
+func partitionMonth(name string) (time.Time, bool) {
+	rest, ok := strings.CutPrefix(name, partitionPrefix)
+	if !ok {
+		return time.Time{}, false
+	}
+	t, err := time.Parse(partitionLayout, rest)
+	return t, err == nil
+}
+
+func monthStart(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+// RequestsPartitioned сообщает, что requests — партиционированная таблица (миграция 0003 применена).
+func (c *Client) RequestsPartitioned(ctx context.Context) (bool, error) {
+	var kind string
+	if err := c.pool.QueryRow(ctx, "SELECT relkind::text FROM pg_class WHERE oid = 'requests'::regclass").Scan(&kind); err != nil {
+		return false, err
+	}
+	return kind == "p", nil
+}
+
+// MaintainPartitions создаёт партиции requests на текущий и ahead следующих месяцев и удаляет
+// партиции, все записи которых старше maxAge (0 — не удалять). Удаление партиции — дешёвая
+// альтернатива построчному DELETE retention. Если archiveDir не пуст, записи партиции перед
+// удалением сохраняются в gzip NDJSON (как в EnforceRetention).
+func (c *Client) MaintainPartitions(ctx context.Context, now time.Time, ahead int, maxAge time.Duration, archiveDir string) (PartitionResult, error) {
+	var res PartitionResult
+	existing, err := c.partitions(ctx)
+	if err != nil {
+		return res, err
+	}
+	var errs []error
+	first := monthStart(now)
+	for i := 0; i <= ahead; i++ {
+		from := first.AddDate(0, i, 0)
+		name := PartitionName(from)
+		if existing[name] {
+			continue
+		}
+		q := fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s PARTITION OF requests FOR VALUES FROM ('%s') TO ('%s')",
+			pgx.Identifier{name}.Sanitize(), from.Format(time.RFC3339), from.AddDate(0, 1, 0).Format(time.RFC3339))
+		// строки этого месяца в requests_default не дают создать партицию — сообщаем и продолжаем
+		if _, err := c.pool.Exec(ctx, q); err != nil {
+			errs = append(errs, fmt.Errorf("create %s: %w", name, err))
+			continue
+		}
+		res.Created = append(res.Created, name)
+	}
+
+	if maxAge > 0 {
+		cutoff := now.Add(-maxAge)
+		for name := range existing {
+			month, ok := partitionMonth(name)
+			if !ok || month.AddDate(0, 1, 0).After(cutoff) { // в партиции есть записи моложе maxAge
+				continue
+			}
+			archive, err := c.dropPartition(ctx, name, archiveDir)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("drop %s: %w", name, err))
+				continue
+			}
+			res.Dropped = append(res.Dropped, name)
+			if archive != "" {
+				res.Archives = append(res.Archives, archive)
+			}
+		}
+	}
+	return res, errors.Join(errs...)
+}
+
+// partitions возвращает имена партиций requests.
+func (c *Client) partitions(ctx context.Context) (map[string]bool, error) {
+	rows, err := c.pool.Query(ctx, `SELECT c.relname::text FROM pg_inherits i
+		JOIN pg_class c ON c.oid = i.inhrelid WHERE i.inhparent = 'requests'::regclass`)
+	if err != nil {
+		return nil, err
+	}
+	names, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, err
+	}
+	out := make(map[string]bool, len(names))
+	for _, n := range names {
+		out[n] = true
+	}
+	return out, nil
+}
+
+// dropPartition отсоединяет партицию и удаляет её в одной транзакции, предварительно
+// сохранив записи в архив (если задан archiveDir).
+func (c *Client) dropPartition(ctx context.Context, name, archiveDir string) (path string, err error) {
+	tx, err := c.pool.Begin(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = tx.Rollback(ctx) }() // после Commit ничего не делает
+	ident := pgx.Identifier{name}.Sanitize()
+	if _, err := tx.Exec(ctx, "ALTER TABLE requests DETACH PARTITION "+ident); err != nil {
+		return "", err
+	}
+	if archiveDir != "" {
+		if path, err = archivePartition(ctx, tx, ident, archiveDir, name); err != nil {
+			return "", fmt.Errorf("archive: %w", err)
+		}
+	}
+	if _, err := tx.Exec(ctx, "DROP TABLE "+ident); err != nil {
+		return "", err
+	}
+	return path, tx.Commit(ctx)
+}
+
+func archivePartition(ctx context.Context, tx pgx.Tx, ident, dir, name string) (path string, err error) {
+	arch, err := openArchive(dir, name)
+	if err != nil {
+		return "", err
+	}
+	defer func() { err = errors.Join(err, arch.close()) }()
+	rows, err := tx.Query(ctx, "SELECT * FROM "+ident+" ORDER BY id")
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+	chunk := make([]archivedRow, 0, 1000)
+	for rows.Next() {
+		r, err := pgx.RowToMap(rows)
+		if err != nil {
+			return "", err
+		}
+		if chunk = append(chunk, r); len(chunk) == cap(chunk) {
+			if err := arch.write(chunk); err != nil {
+				return "", err
+			}
+			chunk = chunk[:0]
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return "", err
+	}
+	return arch.path, arch.write(chunk)
+}
@@ -0,0 +1,152 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"k8s-hw/internal/config"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// replica — пул к одной read-реплике. Реплика получает чтения, только пока последняя
+// проверка (CheckReplicas) успешна; до первой проверки чтения идут на primary.
+type replica struct {
+	host    string
+	pool    *pgxpool.Pool
+	healthy atomic.Bool
+}
+
+// newReplicas создаёт пулы к APP_POSTGRES_REPLICA_HOSTS с параметрами primary (pgx подключается лениво,
+// поэтому недоступная реплика не мешает старту).
+func newReplicas(ctx context.Context, primary *pgxpool.Config, pc config.Postgres) ([]*replica, error) {
+	var out []*replica
+	for _, hp := range pc.ReplicaHosts {
+		host, port, err := SplitHostPort(hp, primary.ConnConfig.Port)
+		if err != nil {
+			closeReplicas(out)
+			return nil, err
+		}
+		cfg := primary.Copy()
+		cfg.ConnConfig.Host, cfg.ConnConfig.Port = host, port
+		cfg.ConnConfig.Fallbacks = nil
+		if tc := cfg.ConnConfig.TLSConfig; tc != nil && tc.ServerName != "" { // verify-full сверяет имя хоста
+			tc = tc.Clone()
+			tc.ServerName = host
+			cfg.ConnConfig.TLSConfig = tc
+		}
+		pool, err := pgxpool.NewWithConfig(ctx, cfg)
+		if err != nil {
+			closeReplicas(out)
+			return nil, fmt.Errorf("create replica pool %s: %w", hp, err)
+		}
+		out = append(out, &replica{host: hp, pool: pool})
+	}
+	return out, nil
+}
+
+// SplitHostPort разбирает host или host:port; без порта используется defaultPort.
+func SplitHostPort(hp string, defaultPort uint16) (string, uint16, error) {
+	if !strings.Contains(hp, ":") {
+		return hp, defaultPort, nil
+	}
+	host, portStr, err := net.SplitHostPort(hp)
+	if err != nil {
+		return "", 0, fmt.Errorf("replica %q: %w", hp, err)
+	}
+	port, err := strconv.ParseUint(portStr, 10, 16)
+	if err != nil || port == 0 {
+		return "", 0, fmt.Errorf("replica %q: invalid port", hp)
+	}
+	return host, uint16(port), nil
+}
+
+func closeReplicas(rs []*replica) {
+	for _, r := range rs {
+		r.pool.Close()
+	}
+}
+
+// reader выбирает здоровую реплику по кругу; nil — читать с primary.
+func (c *Client) reader() *replica {
+	n := len(c.replicas)
+	if n == 0 {
+		return nil
+	}
+	start := int(c.next.Add(1))
+	for i := range n {
+		if r := c.replicas[(start+i)%n]; r.healthy.Load() {
+			return r
+		}
+	}
+	return nil
+}
+
+// read выполняет запрос только на чтение на реплике, а при ошибке соединения с ней —
+// помечает реплику нездоровой и повторяет запрос на primary. Ошибки самого запроса
+// (ответ сервера с SQLSTATE) и отмена контекста не повторяются. На primary чтение
+// идемпотентно и после failover повторяется (см. write).
+func (c *Client) read(ctx context.Context, fn func(*pgxpool.Pool) error) error {
+	if r := c.reader(); r != nil {
+		err := fn(r.pool)
+		var pgErr *pgconn.PgError
+		if err == nil || errors.As(err, &pgErr) || ctx.Err() != nil {
+			return err
+		}
+		if r.healthy.CompareAndSwap(true, false) {
+			log.Printf("db: replica %s unavailable, reading from primary: %v", r.host, err)
+		}
+	}
+	return c.write(ctx, true, func() error { return fn(c.pool) })
+}
+
+// ReplicaStatus — состояние read-реплики.
+type ReplicaStatus struct {
+	Host    string
+	Healthy bool
+}
+
+// CheckReplicas пингует реплики и включает/выключает их для чтения. Возвращает ошибку,
+// если реплики заданы, но ни одна не доступна (чтения при этом идут на primary).
+func (c *Client) CheckReplicas(ctx context.Context) error {
+	if len(c.replicas) == 0 {
+		return nil
+	}
+	var errs []error
+	for _, r := range c.replicas {
+		pctx, cancel := context.WithTimeout(ctx, time.Second)
+		err := r.pool.Ping(pctx)
+		cancel()
+		if ok := err == nil; r.healthy.Swap(ok) != ok {
+			if ok {
+				log.Printf("db: replica %s is healthy, routing reads to it", r.host)
+			} else {
+				log.Printf("db: replica %s unavailable: %v", r.host, err)
+			}
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", r.host, err))
+		}
+	}
+	if len(errs) == len(c.replicas) {
+		return fmt.Errorf("no healthy replicas, reads go to primary: %w", errors.Join(errs...))
+	}
+	return nil
+}
+
+// Replicas возвращает состояние реплик.
+func (c *Client) Replicas() []ReplicaStatus {
+	out := make([]ReplicaStatus, 0, len(c.replicas))
+	for _, r := range c.replicas {
+		out = append(out, ReplicaStatus{Host: r.host, Healthy: r.healthy.Load()})
+	}
+	return out
+}
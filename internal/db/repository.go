@@ -0,0 +1,48 @@
+package db
+
+import (
+	"context"
+	"time"
+)
+
+// RequestRepository хранит записи о запросах (таблица requests).
+type RequestRepository interface {
+	InsertRequest(ctx context.Context, r NewRequest) (id int64, createdAt time.Time, err error)
+	// InsertRequests вставляет пачку записей (CreatedAt должен быть задан) одной операцией.
+	InsertRequests(ctx context.Context, rs []NewRequest) error
+	// ImportRequests потоково вставляет записи из src и возвращает их число и диапазон id.
+	ImportRequests(ctx context.Context, src RequestSource) (ImportResult, error)
+	// ListRequests возвращает последние limit записей, новые первыми.
+	ListRequests(ctx context.Context, limit int) ([]Request, error)
+}
+
+// CronRunRepository хранит отметки запусков CronJob (таблица cron_runs).
+type CronRunRepository interface {
+	InsertCronRun(ctx context.Context) (id int64, executedAt time.Time, err error)
+	// LastCronRun возвращает время последнего запуска (нулевое, если запусков не было).
+	LastCronRun(ctx context.Context) (time.Time, error)
+}
+
+// StatsRepository агрегирует requests по времени для /stats.
+type StatsRepository interface {
+	Stats(ctx context.Context, q StatsQuery) ([]StatsPoint, error)
+}
+
+// ExportRepository построчно выгружает таблицы для /db/export/*.
+type ExportRepository interface {
+	ExportRequests(ctx context.Context, tr TimeRange, fn func(Request) error) error
+	ExportCronRuns(ctx context.Context, tr TimeRange, fn func(CronRun) error) error
+}
+
+// Repository — всё хранилище приложения: Postgres (*Client) или память (*Memory).
+type Repository interface {
+	RequestRepository
+	CronRunRepository
+	StatsRepository
+	ExportRepository
+}
+
+var (
+	_ Repository = (*Client)(nil)
+	_ Repository = (*Memory)(nil)
+)
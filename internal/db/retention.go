@@ -0,0 +1,167 @@
+package db
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// RetentionPolicy — ограничение хранения для таблицы: записи старше MaxAge и сверх MaxRows
+// самых новых удаляются. Нулевое значение ограничения не применяется.
+type RetentionPolicy struct {
+	Table   string
+	MaxAge  time.Duration
+	MaxRows int64
+}
+
+// retentionColumns — столбец времени таблиц, к которым применяется retention (config.RetentionTables).
+var retentionColumns = map[string]string{
+	"requests":  "created_at",
+	"cron_runs": "executed_at",
+}
+
+// RetentionResult — итог применения политики к таблице.
+type RetentionResult struct {
+	Deleted int64
+	Archive string // путь к архиву (пусто, если архивирование выключено или ничего не удалено)
+}
+
+// archivedRow — строка архива NDJSON: все столбцы удалённой записи по именам, чтобы архив
+// не отставал от схемы при добавлении столбцов.
+type archivedRow map[string]any
+
+// EnforceRetention удаляет записи, нарушающие политику, пачками по batch в отдельных транзакциях:
+// таблица надолго не блокируется, а прерванный запуск продолжится в следующий раз. Если archiveDir
+// не пуст, удаляемые записи сначала дописываются в archiveDir/<table>-<время>.ndjson.gz —
+// транзакция фиксируется только после того, как пачка записана и сброшена на диск.
+func (c *Client) EnforceRetention(ctx context.Context, p RetentionPolicy, batch int, archiveDir string) (res RetentionResult, err error) {
+	tsCol, ok := retentionColumns[p.Table]
+	if !ok {
+		return res, fmt.Errorf("unknown table %q", p.Table)
+	}
+	table := pgx.Identifier{p.Table}.Sanitize()
+
+	var conds []string
+	var args []any
+	if p.MaxRows > 0 {
+		// id самой старой из MaxRows последних записей: всё, что раньше, удаляется
+		var keepFrom int64
+		err := c.pool.QueryRow(ctx, "SELECT id FROM "+table+" ORDER BY id DESC OFFSET $1 LIMIT 1", p.MaxRows-1).Scan(&keepFrom)
+		switch {
+		case errors.Is(err, pgx.ErrNoRows): // записей не больше MaxRows
+		case err != nil:
+			return res, err
+		default:
+			args = append(args, keepFrom)
+			conds = append(conds, fmt.Sprintf("id < $%d", len(args)))
+		}
+	}
+	if p.MaxAge > 0 {
+		args = append(args, time.Now().Add(-p.MaxAge))
+		conds = append(conds, fmt.Sprintf("%s < $%d", pgx.Identifier{tsCol}.Sanitize(), len(args)))
+	}
+	if len(conds) == 0 {
+		return res, nil
+	}
+	args = append(args, batch)
+	del := fmt.Sprintf("DELETE FROM %[1]s WHERE id IN (SELECT id FROM %[1]s WHERE %[2]s ORDER BY id LIMIT $%[3]d) RETURNING *",
+		table, strings.Join(conds, " OR "), len(args))
+
+	var arch *archive
+	defer func() {
+		if arch != nil {
+			err = errors.Join(err, arch.close())
+		}
+	}()
+	for {
+		n, err := c.deleteBatch(ctx, del, args, func(rows []archivedRow) error {
+			if archiveDir == "" {
+				return nil
+			}
+			if arch == nil {
+				a, err := openArchive(archiveDir, p.Table)
+				if err != nil {
+					return err
+				}
+				arch, res.Archive = a, a.path
+			}
+			return arch.write(rows)
+		})
+		res.Deleted += n
+		if err != nil || n < int64(batch) {
+			return res, err
+		}
+	}
+}
+
+// deleteBatch удаляет одну пачку и передаёт удалённые строки в archive до фиксации транзакции.
+func (c *Client) deleteBatch(ctx context.Context, del string, args []any, archive func([]archivedRow) error) (int64, error) {
+	tx, err := c.pool.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }() // после Commit ничего не делает
+	rows, err := tx.Query(ctx, del, args...)
+	if err != nil {
+		return 0, err
+	}
+	deleted, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (archivedRow, error) { return pgx.RowToMap(row) })
+	if err != nil {
+		return 0, err
+	}
+	if len(deleted) == 0 {
+		return 0, nil
+	}
+	if err := archive(deleted); err != nil {
+		return 0, fmt.Errorf("archive: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return 0, err
+	}
+	return int64(len(deleted)), nil
+}
+
+// archive — gzip NDJSON файл с удалёнными записями.
+type archive struct {
+	path string
+	f    *os.File
+	gz   *gzip.Writer
+}
+
+func openArchive(dir, table string) (*archive, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	path := filepath.Join(dir, fmt.Sprintf("%s-%s.ndjson.gz", table, time.Now().UTC().Format("20060102T150405Z")))
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	return &archive{path: path, f: f, gz: gzip.NewWriter(f)}, nil
+}
+
+// write дописывает строки и сбрасывает их на диск: после возврата без ошибки их можно удалять из БД.
+func (a *archive) write(rows []archivedRow) error {
+	enc := json.NewEncoder(a.gz)
+	for _, r := range rows {
+		if err := enc.Encode(r); err != nil {
+			return err
+		}
+	}
+	if err := a.gz.Flush(); err != nil {
+		return err
+	}
+	return a.f.Sync()
+}
+
+func (a *archive) close() error {
+	return errors.Join(a.gz.Close(), a.f.Close())
+}
@@ -0,0 +1,218 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Шаг агрегации и группировка /stats.
+const (
+	BucketMinute = "minute"
+	BucketHour   = "hour"
+
+	GroupNone = ""
+	GroupPod  = "pod"
+	GroupPath = "path"
+)
+
+// MaxStatsBuckets ограничивает число интервалов в одном ответе /stats.
+const MaxStatsBuckets = 10000
+
+// groupColumns — столбец requests/requests_rollup для группировки.
+var groupColumns = map[string]string{
+	GroupNone: "''::text",
+	GroupPod:  "pod_name",
+	GroupPath: "path",
+}
+
+// StatsQuery — параметры агрегации: интервалы шага Bucket, покрывающие [From, To), с группировкой Group.
+type StatsQuery struct {
+	Bucket string
+	From   time.Time
+	To     time.Time
+	Group  string
+}
+
+// StatsPoint — число запросов в интервале, начинающемся в Bucket; Key — pod или path (пусто без группировки).
+type StatsPoint struct {
+	Bucket time.Time `json:"bucket"`
+	Key    string    `json:"key,omitempty"`
+	Count  int64     `json:"count"`
+}
+
+func (q StatsQuery) step() time.Duration {
+	if q.Bucket == BucketHour {
+		return time.Hour
+	}
+	return time.Minute
+}
+
+// span возвращает начала первого и последнего интервалов (в UTC).
+func (q StatsQuery) span() (first, last time.Time) {
+	step := q.step()
+	return q.From.UTC().Truncate(step), q.To.UTC().Add(-time.Nanosecond).Truncate(step)
+}
+
+// Validate проверяет шаг, группировку и число интервалов.
+func (q StatsQuery) Validate() error {
+	if q.Bucket != BucketMinute && q.Bucket != BucketHour {
+		return fmt.Errorf("bucket must be minute or hour, got %q", q.Bucket)
+	}
+	if _, ok := groupColumns[q.Group]; !ok {
+		return fmt.Errorf("group must be pod or path, got %q", q.Group)
+	}
+	if !q.From.Before(q.To) {
+		return errors.New("from must be before to")
+	}
+	first, last := q.span()
+	if n := int(last.Sub(first)/q.step()) + 1; n > MaxStatsBuckets {
+		return fmt.Errorf("range spans %d %s buckets, max %d", n, q.Bucket, MaxStatsBuckets)
+	}
+	return nil
+}
+
+// Stats считает запросы по интервалам. Закрытые минуты берутся из requests_rollup (до последнего
+// bucket, который туда записал RollupRequests), хвост — из requests. generate_series заполняет
+// пустые интервалы нулями для каждого ключа, встретившегося в диапазоне.
+func (c *Client) Stats(ctx context.Context, q StatsQuery) ([]StatsPoint, error) {
+	if err := q.Validate(); err != nil {
+		return nil, err
+	}
+	first, last := q.span()
+	keys := "SELECT DISTINCT key FROM agg"
+	if q.Group == GroupNone {
+		keys = "VALUES (''::text)"
+	}
+	sql := fmt.Sprintf(`
+		WITH mark AS (
+			SELECT coalesce(max(bucket) + interval '1 minute', '-infinity') AS ts FROM requests_rollup
+		), src AS (
+			SELECT r.bucket AS ts, r.pod_name, r.path, r.count FROM requests_rollup r, mark
+			WHERE r.bucket < mark.ts AND r.bucket >= $1 AND r.bucket < $3
+			UNION ALL
+			SELECT r.created_at, r.pod_name, r.path, 1 FROM requests r, mark
+			WHERE r.created_at >= greatest(mark.ts, $1) AND r.created_at < $3
+		), agg AS (
+			SELECT date_trunc($4, ts, 'UTC') AS bucket, %[1]s AS key, sum(count)::bigint AS count
+			FROM src GROUP BY 1, 2
+		), keys(key) AS (%[2]s)
+		SELECT s.bucket, k.key, coalesce(a.count, 0)
+		FROM generate_series($1::timestamptz, $2::timestamptz, ('1 ' || $4)::interval) AS s(bucket)
+		CROSS JOIN keys k
+		LEFT JOIN agg a ON a.bucket = s.bucket AND a.key = k.key
+		ORDER BY 1, 2`, groupColumns[q.Group], keys)
+	var out []StatsPoint
+	err := c.read(ctx, func(p *pgxpool.Pool) error {
+		rows, err := p.Query(ctx, sql, first, last, last.Add(q.step()), q.Bucket)
+		if err != nil {
+			return err
+		}
+		out, err = pgx.CollectRows(rows, pgx.RowToStructByPos[StatsPoint])
+		return err
+	})
+	return out, err
+}
+
+// rollupLockKey — ключ транзакционной advisory-блокировки requests_rollup: пересчёт RollupRequests
+// и досчёт импорта (rollupImported) не перезаписывают изменения друг друга.
+const rollupLockKey = 0x726f6c6c7570 // "rollup"
+
+// RollupRequests пересчитывает поминутные агрегаты requests_rollup для закрытых минут (до now),
+// начиная за lookback до последнего записанного bucket: так учитываются записи, вставленные
+// с опозданием (write-behind очередь). Импорт задним числом досчитывает rollupImported.
+// Возвращает число обновлённых строк.
+func (c *Client) RollupRequests(ctx context.Context, now time.Time, lookback time.Duration) (int64, error) {
+	var n int64
+	err := c.write(ctx, true, func() error {
+		return pgx.BeginFunc(ctx, c.pool, func(tx pgx.Tx) error {
+			if _, err := tx.Exec(ctx, "SELECT pg_advisory_xact_lock($1)", rollupLockKey); err != nil {
+				return err
+			}
+			tag, err := tx.Exec(ctx, `
+			WITH mark AS (
+				SELECT coalesce(max(bucket) - $2::interval, '-infinity') AS ts FROM requests_rollup
+			)
+			INSERT INTO requests_rollup (bucket, pod_name, path, count)
+			SELECT date_trunc('minute', r.created_at, 'UTC'), r.pod_name, r.path, count(*)
+			FROM requests r, mark
+			WHERE r.created_at >= mark.ts AND r.created_at < date_trunc('minute', $1::timestamptz, 'UTC')
+			GROUP BY 1, 2, 3
+			ON CONFLICT (bucket, pod_name, path) DO UPDATE SET count = EXCLUDED.count`,
+				now, fmt.Sprintf("%d seconds", int64(lookback/time.Second)))
+			n = tag.RowsAffected()
+			return err
+		})
+	})
+	return n, err
+}
+
+// rollupImported добавляет записи временной таблицы requests_import к уже свёрнутым минутам
+// requests_rollup (до последнего bucket): Stats берёт эти минуты только из requests_rollup, и импорт
+// задним числом иначе не попал бы в статистику. Счётчики увеличиваются, а не пересчитываются по
+// requests, чтобы не потерять записи, уже удалённые retention. Вызывается в транзакции импорта.
+func rollupImported(ctx context.Context, tx pgx.Tx) error {
+	if _, err := tx.Exec(ctx, "SELECT pg_advisory_xact_lock($1)", rollupLockKey); err != nil {
+		return err
+	}
+	_, err := tx.Exec(ctx, `
+		WITH mark AS (
+			SELECT max(bucket) + interval '1 minute' AS ts FROM requests_rollup
+		)
+		INSERT INTO requests_rollup (bucket, pod_name, path, count)
+		SELECT date_trunc('minute', i.created_at, 'UTC'), i.pod_name, i.path, count(*)
+		FROM requests_import i, mark
+		WHERE i.created_at < mark.ts
+		GROUP BY 1, 2, 3
+		ON CONFLICT (bucket, pod_name, path) DO UPDATE SET count = requests_rollup.count + EXCLUDED.count`)
+	return err
+}
+
+// Stats считает запросы по интервалам так же, как Client.Stats, но прямо по записям в памяти.
+func (m *Memory) Stats(_ context.Context, q StatsQuery) ([]StatsPoint, error) {
+	if err := q.Validate(); err != nil {
+		return nil, err
+	}
+	first, last := q.span()
+	end, step := last.Add(q.step()), q.step()
+	type cell struct {
+		bucket time.Time
+		key    string
+	}
+	counts := map[cell]int64{}
+	keys := []string{""}
+	if q.Group != GroupNone {
+		keys = nil
+	}
+	m.mu.Lock()
+	for _, r := range m.requests {
+		if r.CreatedAt.Before(first) || !r.CreatedAt.Before(end) {
+			continue
+		}
+		key := ""
+		switch q.Group {
+		case GroupPod:
+			key = r.Pod
+		case GroupPath:
+			key = r.Path
+		}
+		if q.Group != GroupNone && !slices.Contains(keys, key) {
+			keys = append(keys, key)
+		}
+		counts[cell{r.CreatedAt.UTC().Truncate(step), key}]++
+	}
+	m.mu.Unlock()
+	slices.Sort(keys)
+	var out []StatsPoint
+	for b := first; !b.After(last); b = b.Add(step) {
+		for _, k := range keys {
+			out = append(out, StatsPoint{Bucket: b, Key: k, Count: counts[cell{b, k}]})
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,77 @@
+package db
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"k8s-hw/internal/config"
+)
+
+// CheckTLSFiles проверяет сертификаты из конфигурации: CA bundle и клиентский сертификат
+// читаются и разбираются, ключ соответствует сертификату, и каждый сертификат действует на now.
+// Возвращает nil, если файлы TLS не заданы.
+func CheckTLSFiles(pc config.Postgres, now time.Time) error {
+	var errs []error
+	if pc.SSLRootCert != "" {
+		certs, err := readCerts(pc.SSLRootCert)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("sslrootcert: %w", err))
+		}
+		for _, c := range certs {
+			if err := validAt(c, now); err != nil {
+				errs = append(errs, fmt.Errorf("sslrootcert: %w", err))
+			}
+		}
+	}
+	if pc.SSLCert != "" && pc.SSLKey != "" {
+		pair, err := tls.LoadX509KeyPair(pc.SSLCert, pc.SSLKey)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("sslcert/sslkey: %w", err))
+		} else if err := validAt(pair.Leaf, now); err != nil {
+			errs = append(errs, fmt.Errorf("sslcert: %w", err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func readCerts(path string) ([]*x509.Certificate, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var out []*x509.Certificate
+	for {
+		var block *pem.Block
+		block, b = pem.Decode(b)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		c, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, c)
+	}
+	if len(out) == 0 {
+		return nil, fmt.Errorf("no PEM certificates in %s", path)
+	}
+	return out, nil
+}
+
+func validAt(c *x509.Certificate, now time.Time) error {
+	switch {
+	case now.After(c.NotAfter):
+		return fmt.Errorf("certificate %q expired at %s", c.Subject.CommonName, c.NotAfter.UTC().Format(time.RFC3339))
+	case now.Before(c.NotBefore):
+		return fmt.Errorf("certificate %q is not valid until %s", c.Subject.CommonName, c.NotBefore.UTC().Format(time.RFC3339))
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package handler
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+	"time"
+
+	"k8s-hw/internal/lifecycle"
+)
+
+var inflight = lifecycle.NewTracker()
+
+// TrackInFlight оборачивает корневой обработчик учётом запросов в обработке.
+func TrackInFlight(h http.Handler) http.Handler { return inflight.Middleware(h) }
+
+// InFlightCount возвращает число запросов в обработке.
+func InFlightCount() int { return inflight.Count() }
+
+// InFlight возвращает запросы в обработке, самые старые первыми.
+func InFlight() []lifecycle.Request { return inflight.Snapshot() }
+
+// AdminOnly защищает служебные эндпоинты bearer-токеном из APP_ADMIN_TOKEN.
+// Пока токен не задан, эндпоинты открыты (локальная разработка).
+func AdminOnly(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token := live.Load().adminToken
+		if token != "" {
+			got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="admin"`)
+				writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
+				return
+			}
+		}
+		next(w, r)
+	}
+}
+
+// swagger:route GET /admin/config admin effectiveConfig
+// Returns effective configuration with secrets redacted and the source of every value.
+// responses:
+//
+//	200: configResponse
+//	401: errorResponse
+func EffectiveConfig(w http.ResponseWriter, _ *http.Request) {
+	cfg := effective.Load()
+	writeJSON(w, http.StatusOK, map[string]any{
+		"config":  cfg.Tree(),
+		"sources": cfg.Sources(),
+	})
+}
+
+// swagger:route GET /admin/inflight admin inFlight
+// Returns requests currently being processed (useful while draining).
+// responses:
+//
+//	200: inFlightResponse
+//	401: errorResponse
+func InFlightRequests(w http.ResponseWriter, _ *http.Request) {
+	reqs := inflight.Snapshot()
+	items := make([]map[string]any, 0, len(reqs))
+	for _, r := range reqs {
+		items = append(items, map[string]any{
+			"method":     r.Method,
+			"path":       r.Path,
+			"durationMs": time.Since(r.Since).Milliseconds(),
+		})
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"draining": draining.Load(),
+		"count":    len(reqs),
+		"requests": items,
+	})
+}
@@ -1,76 +1,128 @@
 package handler
 
 import (
-	"context"
 	"encoding/json"
+	"log"
 	"net/http"
-	"sync"
+	"sync/atomic"
 	"time"
 
 	"k8s-hw/internal/config"
 	"k8s-hw/internal/db"
+	"k8s-hw/internal/mask"
 )
 
 // Version VersionHandler задаётся через -ldflags "-X k8s-hw/internal/api.VersionHandler=..."
 var Version = "latest"
 
-var (
-	startTime      = time.Now()
-	warmupDur      = time.Second
+// reloadable — значения, которые обновляются на лету при изменении смонтированных ConfigMap/Secret.
+type reloadable struct {
 	configMapVal   string
 	secretUsername string
 	secretPassword string
-	dataDir        string
-	podName        string
-	pgClient       *db.Client
-	postgresCfg    config.Postgres
-	wantDB         bool
-	dbMu           sync.Mutex
+	adminToken     string
+	secretsDir     string
+	secretsPrefix  string
+	maskDefault    mask.Strategy
+	masks          map[string]mask.Strategy
+}
+
+var (
+	startTime = time.Now()
+	warmupDur = time.Second
+	live      atomic.Pointer[reloadable]
+	effective atomic.Pointer[config.Config]
+	dataDir   string
+	podName   string
+	dbm       = db.NewManager(config.Postgres{})
+	memRepo   = db.NewMemory()
+	// batcher — очередь write-behind вставок; nil в режиме APP_REQUESTS_WRITE_MODE=sync.
+	batcher *db.Batcher
 )
 
 // InitConfig инициализирует внутренние параметры из config.Config
 func InitConfig(cfg config.Config) {
 	warmupDur = cfg.ReadinessWarmup()
-	configMapVal = cfg.ConfigMapEnvVar
-	secretUsername = cfg.SecretUsername
-	secretPassword = cfg.SecretPassword
+	storeReloadable(cfg)
 	dataDir = cfg.DataDir
 	podName = cfg.PodName
-	postgresCfg = cfg.Postgres
-	wantDB = postgresCfg.User != "" && postgresCfg.DB != "" && postgresCfg.Host != ""
+	dbm = db.NewManager(cfg.Postgres)
+	memRepo = db.NewMemory()
+	batcher = nil
+	if cfg.RequestsWriteMode == "async" {
+		batcher = db.NewBatcher(func() (db.RequestRepository, error) { return repository() },
+			cfg.RequestsBatchSize, cfg.RequestsQueueSize, cfg.RequestsBatchFlush())
+	}
 	startTime = time.Now()
+	registerChecks(cfg)
 }
 
-// SetDB передаёт и сохраняет клиент Postgres
-func SetDB(c *db.Client) { pgClient = c }
-
-// SetStartTime позволяет тестам переопределять момент запуска для проверки /readyz
-func SetStartTime(t time.Time) { startTime = t }
+func storeReloadable(cfg config.Config) {
+	v := &reloadable{
+		configMapVal:   cfg.ConfigMapEnvVar,
+		secretUsername: cfg.SecretUsername,
+		secretPassword: cfg.SecretPassword,
+		adminToken:     cfg.AdminToken,
+		secretsDir:     cfg.SecretsDir,
+		secretsPrefix:  cfg.SecretsPrefix,
+		maskDefault:    parseMask(cfg.SecretMaskDefault),
+		masks:          make(map[string]mask.Strategy, len(cfg.SecretMasks)),
+	}
+	for k, s := range cfg.SecretMasks {
+		v.masks[k] = parseMask(s)
+	}
+	live.Store(v)
+	effective.Store(&cfg)
+}
 
-// ensureDB пытается (лениво) инициализировать клиент, если он требуется и ещё не создан.
-func ensureDB(ctx context.Context) error {
-	if !wantDB { // БД не обязательна — пропускаем
-		return nil
+// parseMask — неизвестная стратегия не должна раскрывать секрет, поэтому откатываемся к full.
+func parseMask(s string) mask.Strategy {
+	if s == "" {
+		return mask.Full
 	}
-	if pgClient != nil { // уже есть
-		return nil
+	st, err := mask.Parse(s)
+	if err != nil {
+		log.Printf("WARN: %v, using %s", err, mask.Full)
+		return mask.Full
+	}
+	return st
+}
+
+// ApplyConfig атомарно подменяет значения для /test-env и /secret. Если изменились параметры
+// Postgres, менеджер БД закрывает текущий пул и подключается заново с новыми учётными данными.
+func ApplyConfig(cfg config.Config) {
+	storeReloadable(cfg)
+	if dbm.Reconfigure(cfg.Postgres) {
+		registerDBChecks(cfg)
+		log.Printf("Postgres config changed host=%s db=%s, recreating pool", cfg.Postgres.Host, cfg.Postgres.DB)
 	}
-	dbMu.Lock()
-	defer dbMu.Unlock()
-	if pgClient != nil { // двойная проверка после захвата
-		return nil
+}
+
+// DB возвращает менеджер подключения к Postgres (его цикл Run запускает main).
+func DB() *db.Manager { return dbm }
+
+// repository возвращает хранилище: Postgres, если он сконфигурирован (ошибка, пока пул не
+// создан), иначе хранилище в памяти.
+func repository() (db.Repository, error) {
+	if !dbm.Enabled() {
+		return memRepo, nil
 	}
-	// короткий таймаут на попытку
-	cctx, cancel := context.WithTimeout(ctx, 2*time.Second)
-	defer cancel()
-	client, err := db.New(cctx, postgresCfg)
+	c, err := dbm.Client()
 	if err != nil {
-		return err
+		return nil, err
 	}
-	pgClient = client
-	return nil
+	return c, nil
 }
 
+// Batcher возвращает очередь write-behind вставок (nil в синхронном режиме); её цикл Run запускает main.
+func Batcher() *db.Batcher { return batcher }
+
+// CloseDB закрывает текущий пул Postgres (если он был создан) и сообщает, был ли он.
+func CloseDB() bool { return dbm.Close() }
+
+// SetStartTime позволяет тестам переопределять момент запуска для проверки /readyz
+func SetStartTime(t time.Time) { startTime = t }
+
 // writeJSON helper
 func writeJSON(w http.ResponseWriter, status int, v any) {
 	w.Header().Set("Content-Type", "application/json")
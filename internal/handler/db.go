@@ -1,22 +1,88 @@
 package handler
 
-import "net/http"
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"k8s-hw/internal/db"
+)
+
+// maxImportBody ограничивает тело POST /db/requests/batch.
+const maxImportBody = 64 << 20
+
+// Requests обслуживает /db/requests: GET — список последних записей, POST — новая запись.
+func Requests(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		ListRequests(w, r)
+	default:
+		InsertRequest(w, r)
+	}
+}
+
+// swagger:route GET /db/requests db listRequests
+// Lists latest db records (served by a read replica when available).
+// responses:
+//
+//	200: dbListResponse
+func ListRequests(w http.ResponseWriter, r *http.Request) {
+	limit := 50
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > 1000 {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "limit must be 1..1000"})
+			return
+		}
+		limit = n
+	}
+	repo, err := repository()
+	if err != nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": err.Error()})
+		return
+	}
+	items, err := repo.ListRequests(r.Context(), limit)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"items": items})
+}
 
 // swagger:route POST /db/requests db insertRequest
-// Creates db record with request timestamp.
+// Creates db record with request timestamp. With APP_REQUESTS_WRITE_MODE=async the record is
+// queued and written in batches (202); ?sync=true forces a synchronous insert that returns the id.
 // responses:
 //
 //	200: dbInsertResponse
+//	202: dbQueuedResponse
+//	503: errorResponse
 func InsertRequest(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		w.WriteHeader(http.StatusMethodNotAllowed)
 		return
 	}
-	if pgClient == nil {
-		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "db client not initialized"})
+	if batcher != nil && r.URL.Query().Get("sync") != "true" {
+		ts := time.Now().UTC()
+		if err := batcher.Enqueue(db.NewRequest{CreatedAt: ts, Pod: podName, Path: r.URL.Path}); err != nil {
+			w.Header().Set("Retry-After", "1")
+			writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusAccepted, map[string]any{"queued": true, "createdAt": ts})
 		return
 	}
-	id, ts, err := pgClient.InsertRequest(r.Context())
+	repo, err := repository()
+	if err != nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": err.Error()})
+		return
+	}
+	id, ts, err := repo.InsertRequest(r.Context(), db.NewRequest{Pod: podName, Path: r.URL.Path})
 	if err != nil {
 		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
 		return
@@ -26,3 +92,132 @@ func InsertRequest(w http.ResponseWriter, r *http.Request) {
 		"createdAt": ts,
 	})
 }
+
+// importRecord — одна запись тела POST /db/requests/batch; без createdAt используется текущее время,
+// без pod/path — имя пода и путь самого batch-запроса.
+type importRecord struct {
+	CreatedAt *time.Time `json:"createdAt"`
+	Pod       string     `json:"pod"`
+	Path      string     `json:"path"`
+}
+
+// swagger:route POST /db/requests/batch db importRequests
+// Bulk insert via COPY. Body: JSON array or NDJSON of {"createdAt": RFC3339, "pod": "...", "path": "..."} (all optional).
+// The whole batch is inserted in one transaction. Bodies over 64 MiB are rejected with 413.
+// responses:
+//
+//	200: dbImportResponse
+//	400: errorResponse
+//	413: errorResponse
+func ImportRequests(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	repo, err := repository()
+	if err != nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": err.Error()})
+		return
+	}
+	def := db.NewRequest{CreatedAt: time.Now().UTC(), Pod: podName, Path: r.URL.Path}
+	src, srcErr := decodeRecords(http.MaxBytesReader(w, r.Body, maxImportBody), def)
+	res, err := repo.ImportRequests(r.Context(), src)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if *srcErr != nil { // ошибка в теле запроса, а не в БД
+			status, err = http.StatusBadRequest, *srcErr
+			if errors.As(err, new(*http.MaxBytesError)) {
+				status = http.StatusRequestEntityTooLarge
+			}
+		}
+		writeJSON(w, status, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+// decodeRecords потоково читает JSON-массив или NDJSON. Ошибка разбора сохраняется во втором
+// значении, чтобы отличить её от ошибки БД. Незаданные поля записи берутся из def. После
+// закрывающей `]` массива допускаются только пробельные символы.
+func decodeRecords(body io.Reader, def db.NewRequest) (db.RequestSource, *error) {
+	var decodeErr error
+	br := bufio.NewReader(body)
+	dec := json.NewDecoder(br)
+	started, array, done := false, false, false
+	n := 0
+	fail := func(err error) (db.NewRequest, bool, error) {
+		decodeErr = fmt.Errorf("record %d: %w", n, err)
+		return db.NewRequest{}, false, decodeErr
+	}
+	return func() (db.NewRequest, bool, error) {
+		if done {
+			return db.NewRequest{}, false, nil
+		}
+		if !started {
+			started = true
+			first, err := peekNonSpace(br)
+			if errors.Is(err, io.EOF) {
+				return db.NewRequest{}, false, nil
+			}
+			if err != nil {
+				return fail(err)
+			}
+			if array = first == '['; array {
+				if _, err := dec.Token(); err != nil {
+					return fail(err)
+				}
+			}
+		}
+		if array && !dec.More() {
+			done = true
+			if _, err := dec.Token(); err != nil { // закрывающая ]
+				return fail(err)
+			}
+			if _, err := dec.Token(); !errors.Is(err, io.EOF) {
+				if err == nil {
+					err = errors.New("unexpected data after the array")
+				}
+				decodeErr = err
+				return db.NewRequest{}, false, decodeErr
+			}
+			return db.NewRequest{}, false, nil
+		}
+		n++
+		var rec importRecord
+		if err := dec.Decode(&rec); err != nil {
+			if !array && errors.Is(err, io.EOF) {
+				return db.NewRequest{}, false, nil
+			}
+			return fail(err)
+		}
+		out := def
+		if rec.CreatedAt != nil {
+			out.CreatedAt = *rec.CreatedAt
+		}
+		if rec.Pod != "" {
+			out.Pod = rec.Pod
+		}
+		if rec.Path != "" {
+			out.Path = rec.Path
+		}
+		return out, true, nil
+	}, &decodeErr
+}
+
+// peekNonSpace пропускает пробельные символы и возвращает первый значимый байт, не потребляя его.
+func peekNonSpace(br *bufio.Reader) (byte, error) {
+	for {
+		b, err := br.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		switch b {
+		case ' ', '\t', '\r', '\n':
+			continue
+		}
+		if err := br.UnreadByte(); err != nil {
+			return 0, err
+		}
+		return b, nil
+	}
+}
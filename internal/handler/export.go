@@ -0,0 +1,178 @@
+package handler
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"log"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"k8s-hw/internal/db"
+)
+
+// Форматы выгрузки /db/export/*.
+const (
+	formatCSV    = "csv"
+	formatNDJSON = "ndjson"
+)
+
+// exportFlushEvery — через сколько записей выгрузка сбрасывается клиенту.
+const exportFlushEvery = 1000
+
+// exportFormat выбирает формат по ?format=, затем по Accept; по умолчанию NDJSON.
+func exportFormat(r *http.Request) (string, error) {
+	switch f := r.URL.Query().Get("format"); f {
+	case formatCSV, formatNDJSON:
+		return f, nil
+	case "":
+	default:
+		return "", errors.New("format must be csv or ndjson")
+	}
+	for _, part := range strings.Split(r.Header.Get("Accept"), ",") {
+		mt, _, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		switch mt {
+		case "text/csv":
+			return formatCSV, nil
+		case "application/x-ndjson", "application/ndjson":
+			return formatNDJSON, nil
+		}
+	}
+	return formatNDJSON, nil
+}
+
+// exportWriter пишет записи по мере чтения из хранилища: CSV или по JSON-объекту на строку.
+type exportWriter struct {
+	w       http.ResponseWriter
+	rc      *http.ResponseController
+	csv     *csv.Writer // nil для NDJSON
+	enc     *json.Encoder
+	n       int
+	started bool // в ответ уже ушли байты: статус поменять нельзя
+}
+
+func newExportWriter(w http.ResponseWriter, format, name string, header []string) *exportWriter {
+	e := &exportWriter{w: w, rc: http.NewResponseController(w)}
+	if format == formatCSV {
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		e.csv = csv.NewWriter(e)
+		_ = e.csv.Write(header) // ошибка записи всплывёт при flush
+	} else {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		e.enc = json.NewEncoder(e)
+	}
+	w.Header().Set("Content-Disposition", `attachment; filename="`+name+"."+format+`"`)
+	return e
+}
+
+func (e *exportWriter) Write(p []byte) (int, error) {
+	e.started = true
+	return e.w.Write(p)
+}
+
+// write добавляет запись: v — для NDJSON, record — строка CSV.
+func (e *exportWriter) write(v any, record []string) error {
+	var err error
+	if e.csv != nil {
+		err = e.csv.Write(record)
+	} else {
+		err = e.enc.Encode(v)
+	}
+	if err != nil {
+		return err
+	}
+	if e.n++; e.n%exportFlushEvery == 0 {
+		return e.flush()
+	}
+	return nil
+}
+
+func (e *exportWriter) flush() error {
+	if e.csv != nil {
+		e.csv.Flush()
+		if err := e.csv.Error(); err != nil {
+			return err
+		}
+	}
+	if err := e.rc.Flush(); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return err
+	}
+	return nil
+}
+
+// export разбирает формат и диапазон, выполняет выгрузку run и завершает ответ. Ошибка до первых
+// отправленных байтов возвращается как 500; после — соединение обрывается, чтобы клиент не принял
+// обрезанную выгрузку за полную.
+func export(w http.ResponseWriter, r *http.Request, name string, header []string, run func(context.Context, db.Repository, db.TimeRange, *exportWriter) error) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	format, err := exportFormat(r)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	var tr db.TimeRange
+	if err := queryTimes(r.URL.Query(), &tr.From, &tr.To); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	repo, err := repository()
+	if err != nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": err.Error()})
+		return
+	}
+	e := newExportWriter(w, format, name, header)
+	if err = run(r.Context(), repo, tr, e); err == nil {
+		err = e.flush()
+	}
+	if err == nil {
+		return
+	}
+	if !e.started {
+		w.Header().Del("Content-Disposition")
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	log.Printf("export %s aborted after %d records: %v", name, e.n, err)
+	panic(http.ErrAbortHandler)
+}
+
+func formatTime(t time.Time) string { return t.UTC().Format(time.RFC3339Nano) }
+
+// swagger:route GET /db/export/requests db exportRequests
+// Streams requests as NDJSON (default) or CSV, oldest first. Format: ?format=csv|ndjson or
+// Accept: text/csv | application/x-ndjson. Optional ?from= / ?to= (RFC3339) limit created_at to [from, to).
+// responses:
+//
+//	200: exportResponse
+//	400: errorResponse
+func ExportRequests(w http.ResponseWriter, r *http.Request) {
+	export(w, r, "requests", []string{"id", "createdAt", "pod", "path"}, func(ctx context.Context, repo db.Repository, tr db.TimeRange, e *exportWriter) error {
+		return repo.ExportRequests(ctx, tr, func(rec db.Request) error {
+			return e.write(rec, []string{strconv.FormatInt(rec.ID, 10), formatTime(rec.CreatedAt), rec.Pod, rec.Path})
+		})
+	})
+}
+
+// swagger:route GET /db/export/cron_runs db exportCronRuns
+// Streams cron_runs as NDJSON (default) or CSV, oldest first; same format and range parameters as /db/export/requests.
+// responses:
+//
+//	200: exportResponse
+//	400: errorResponse
+func ExportCronRuns(w http.ResponseWriter, r *http.Request) {
+	export(w, r, "cron_runs", []string{"id", "executedAt"}, func(ctx context.Context, repo db.Repository, tr db.TimeRange, e *exportWriter) error {
+		return repo.ExportCronRuns(ctx, tr, func(rec db.CronRun) error {
+			return e.write(rec, []string{strconv.FormatInt(rec.ID, 10), formatTime(rec.ExecutedAt)})
+		})
+	})
+}
@@ -2,45 +2,230 @@ package handler
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"net/http"
+	"strings"
+	"sync/atomic"
 	"time"
+
+	"k8s-hw/internal/config"
+	"k8s-hw/internal/db"
+	"k8s-hw/internal/health"
+	"k8s-hw/internal/watchdog"
+)
+
+var (
+	registry = health.NewRegistry()
+	// started фиксирует успешный /startupz: после старта probe больше не пересчитывается.
+	started atomic.Bool
+	// draining выставляется при SIGTERM: /readyz отвечает 503, пока listener ещё открыт.
+	draining atomic.Bool
+	// wd — watchdog фоновых компонентов; nil, если APP_WATCHDOG_STALL_SECONDS=0.
+	wd *watchdog.Watchdog
 )
 
+// SetDraining переводит /readyz в fail перед остановкой сервера.
+func SetDraining() { draining.Store(true) }
+
+// Health возвращает реестр проверок, чтобы компоненты вне handler могли регистрировать свои.
+func Health() *health.Registry { return registry }
+
+// Watchdog возвращает watchdog, проверяемый в /healthz (nil, если он выключен).
+func Watchdog() *watchdog.Watchdog { return wd }
+
+// registerChecks собирает стандартные проверки заново для новой конфигурации.
+// Всё, что ходит во внешние зависимости или на диск, выполняется в фоне (см. RunHealthChecks).
+func registerChecks(cfg config.Config) {
+	registry = health.NewRegistry()
+	registry.SetHysteresis(cfg.HealthFailureThreshold, cfg.HealthSuccessThreshold)
+	started.Store(false)
+	wd = nil
+	if stall := cfg.WatchdogStall(); stall > 0 {
+		wd = watchdog.New(stall, cfg.WatchdogDump, cfg.DataDir)
+		registry.Register(health.Liveness, health.Check{Name: "watchdog", Critical: true, Fn: wd.Check})
+	}
+	registry.Register(health.Startup, health.Check{Name: "warmup", Critical: true, Fn: checkWarmup})
+	registry.Register(health.Startup, health.Check{Name: "initial-checks", Critical: true, Fn: checkObserved})
+	draining.Store(false)
+	registry.Register(health.Readiness, health.Check{Name: "warmup", Critical: true, Fn: checkWarmup})
+	registry.Register(health.Readiness, health.Check{Name: "shutdown", Critical: true, Fn: checkDraining})
+	registerDBChecks(cfg)
+	if cfg.DataDir != "" {
+		registry.Register(health.Readiness, health.Check{Name: "storage", Critical: cfg.DependencyRequired("storage"), Background: true, Fn: health.DirWritable(cfg.DataDir)})
+		if cfg.DiskMinFreeMB > 0 {
+			registry.Register(health.Readiness, health.Check{Name: "disk", Critical: cfg.DependencyRequired("disk"), Background: true, Fn: health.DiskFree(cfg.DataDir, uint64(cfg.DiskMinFreeMB)<<20)})
+		}
+	}
+	if cfg.VaultAddr != "" {
+		url := strings.TrimRight(cfg.VaultAddr, "/") + "/v1/sys/health?standbyok=true"
+		registry.Register(health.Readiness, health.Check{Name: "vault", Critical: cfg.DependencyRequired("vault"), Background: true, Fn: health.HTTPStatus(url)})
+	}
+}
+
+// registerDBChecks (пере)регистрирует проверки БД: при hot reload Postgres могут включить или отключить.
+func registerDBChecks(cfg config.Config) {
+	if !dbm.Enabled() {
+		registry.Unregister(health.Readiness, "db")
+		registry.Unregister(health.Readiness, "db-tls")
+		registry.Unregister(health.Readiness, "db-replicas")
+		registry.Unregister(health.Readiness, "cron")
+		return
+	}
+	registry.Register(health.Readiness, health.Check{Name: "db", Critical: cfg.DependencyRequired("db"), Background: true, Timeout: 3 * time.Second, Fn: checkDB})
+	if len(cfg.Postgres.TLSFiles()) > 0 {
+		pc := cfg.Postgres
+		registry.Register(health.Readiness, health.Check{Name: "db-tls", Critical: cfg.DependencyRequired("db"), Background: true, Fn: func(context.Context) error {
+			return db.CheckTLSFiles(pc, time.Now())
+		}})
+	} else {
+		registry.Unregister(health.Readiness, "db-tls")
+	}
+	// без реплик чтения идут на primary: их недоступность не влияет на готовность
+	if len(cfg.Postgres.ReplicaHosts) > 0 {
+		registry.Register(health.Readiness, health.Check{Name: "db-replicas", Background: true, Fn: dbm.CheckReplicas})
+	} else {
+		registry.Unregister(health.Readiness, "db-replicas")
+	}
+	if stale := cfg.CronStaleAfter(); stale > 0 {
+		registry.Register(health.Readiness, health.Check{Name: "cron", Critical: cfg.DependencyRequired("cron"), Background: true, Fn: checkCron(stale)})
+	}
+}
+
+// RunHealthChecks выполняет фоновые проверки каждые interval до отмены ctx.
+func RunHealthChecks(ctx context.Context, interval time.Duration) {
+	registry.RunBackground(ctx, interval)
+}
+
+// checkObserved ждёт первого круга фоновых проверок, чтобы /readyz не отвечал по пустому состоянию.
+func checkObserved(context.Context) error {
+	if !registry.Observed() {
+		return errors.New("waiting for the first round of background checks")
+	}
+	return nil
+}
+
+func checkDraining(context.Context) error {
+	if draining.Load() {
+		return errors.New("draining, shutdown in progress")
+	}
+	return nil
+}
+
+func checkWarmup(context.Context) error {
+	if left := warmupDur - time.Since(startTime); left > 0 {
+		return fmt.Errorf("warming, %s left", left.Round(time.Millisecond))
+	}
+	return nil
+}
+
+// checkDB пингует БД через менеджер подключения; пока пул не создан, отдаёт его состояние.
+func checkDB(ctx context.Context) error {
+	pctx, cancel := context.WithTimeout(ctx, 500*time.Millisecond)
+	defer cancel()
+	if err := dbm.Ping(pctx); err != nil {
+		if errors.Is(err, db.ErrNotConnected) {
+			return err
+		}
+		return fmt.Errorf("ping: %w", err)
+	}
+	return nil
+}
+
+// checkCron считает CronJob зависшим, если последняя запись в cron_runs старше stale.
+func checkCron(stale time.Duration) func(context.Context) error {
+	return func(ctx context.Context) error {
+		repo, err := repository()
+		if err != nil {
+			return nil // недоступность БД отражает проверка db
+		}
+		last, err := repo.LastCronRun(ctx)
+		if err != nil {
+			return err
+		}
+		if last.IsZero() {
+			return errors.New("no cron runs recorded")
+		}
+		if age := time.Since(last); age > stale {
+			return fmt.Errorf("last cron run %s ago (threshold %s)", age.Round(time.Second), stale)
+		}
+		return nil
+	}
+}
+
+// writeReport отдаёт отчёт probe: ключ key = okValue/"degraded"/failValue, списки
+// проваленных (критичных и optional) проверок и, при ?verbose, подробности по каждой проверке.
+func writeReport(w http.ResponseWriter, r *http.Request, key, okValue, failValue string, rep health.Report) {
+	status, value := http.StatusOK, okValue
+	switch rep.Status {
+	case health.StatusFail:
+		status, value = http.StatusServiceUnavailable, failValue
+	case health.StatusDegraded:
+		value = health.StatusDegraded
+	}
+	body := map[string]any{key: value}
+	if len(rep.Failed) > 0 {
+		body["failed"] = rep.Failed
+	}
+	if len(rep.Degraded) > 0 {
+		body["degraded"] = rep.Degraded
+	}
+	if _, verbose := r.URL.Query()["verbose"]; verbose {
+		body["checks"] = rep.Checks
+	}
+	writeJSON(w, status, body)
+}
+
 // swagger:route GET /healthz healthcheck healthz
-// Liveness/health check.
+// Liveness/health check. ?verbose returns per-check status and latency.
 // responses:
 //
 //	200: healthzResponse
-func Healthz(w http.ResponseWriter, _ *http.Request) {
-	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+//	503: healthzResponse
+func Healthz(w http.ResponseWriter, r *http.Request) {
+	rep := registry.Run(r.Context(), health.Liveness)
+	writeReport(w, r, "status", health.StatusOK, health.StatusFail, rep)
 }
 
-// swagger:route GET /readyz healthcheck readyz
-// Readiness check: учитывает время прогрева и готовность БД (если сконфигурирована).
+// swagger:route GET /startupz healthcheck startupz
+// Startup check for startupProbe: warmup and the first round of background checks. Always 200 once passed.
 // responses:
 //
-//	200: readinessResponse
-func Readyz(w http.ResponseWriter, r *http.Request) {
-	// 1. Общий прогрев
-	if time.Since(startTime) < warmupDur {
-		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"ready": "warming"})
+//	200: startupResponse
+//	503: startupResponse
+func Startupz(w http.ResponseWriter, r *http.Request) {
+	if started.Load() {
+		writeJSON(w, http.StatusOK, map[string]string{"started": "true"})
 		return
 	}
-	// 2. Если БД требуется — пытаемся лениво подключиться и пропинговать
-	if err := ensureDB(r.Context()); err != nil {
-		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"ready": "db-connecting"})
-		return
+	rep := registry.Run(r.Context(), health.Startup)
+	if rep.OK() {
+		started.Store(true)
 	}
-	if pgClient != nil {
-		ctx, cancel := context.WithTimeout(r.Context(), 500*time.Millisecond)
-		err := pgClient.Ping(ctx)
-		cancel()
-		if err != nil {
-			writeJSON(w, http.StatusServiceUnavailable, map[string]string{"ready": "db-ping-fail"})
+	writeReport(w, r, "started", "true", "false", rep)
+}
+
+// RequireDependency отвечает 503, пока зависимость name не готова: в деградированном
+// режиме под остаётся в Service, но маршруты, которым нужна зависимость, недоступны.
+func RequireDependency(name string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !registry.Healthy(health.Readiness, name) {
+			w.Header().Set("Retry-After", "5")
+			writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": name + " unavailable (degraded mode)"})
 			return
 		}
+		next(w, r)
 	}
+}
 
-	// 3. Всё готово
-	writeJSON(w, http.StatusOK, map[string]string{"ready": "true"})
+// swagger:route GET /readyz healthcheck readyz
+// Readiness check: warmup, database and registered components. Failed optional dependencies give 200 "degraded".
+// ?verbose returns per-check status and latency.
+// responses:
+//
+//	200: readinessResponse
+//	503: readinessResponse
+func Readyz(w http.ResponseWriter, r *http.Request) {
+	rep := registry.Run(r.Context(), health.Readiness)
+	writeReport(w, r, "ready", "true", "false", rep)
 }
@@ -1,6 +1,15 @@
 package handler
 
-import "net/http"
+import (
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+
+	"k8s-hw/internal/mask"
+	"k8s-hw/internal/watch"
+)
 
 // swagger:route GET /secret secret secret
 // Returns (masked) secret values injected via environment.
@@ -8,20 +17,78 @@ import "net/http"
 //
 //	200: secretResponse
 func Secret(w http.ResponseWriter, _ *http.Request) {
-	masked := ""
-	if len(secretPassword) > 0 {
-		if len(secretPassword) <= 3 {
-			masked = "***"
-		} else {
-			masked = secretPassword[:1] + "***" + secretPassword[len(secretPassword)-1:]
+	v := live.Load()
+	writeJSON(w, http.StatusOK, map[string]string{
+		"username": v.secretUsername,
+		"password": mask.Apply(mask.Partial, v.secretPassword),
+	})
+}
+
+// swagger:route GET /secrets secret secrets
+// Returns every key of the configured secret directory or env prefix, masked per key policy.
+// responses:
+//
+//	200: secretsResponse
+//	500: errorResponse
+func Secrets(w http.ResponseWriter, _ *http.Request) {
+	v := live.Load()
+	var (
+		values map[string]string
+		source string
+	)
+	switch {
+	case v.secretsDir != "":
+		m, err := watch.ReadDir(v.secretsDir)
+		if err != nil {
+			log.Printf("secrets: %v", err) // путь к каталогу клиенту не отдаём
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "cannot read secrets"})
+			return
 		}
+		// как ApplyDirs: маска и отпечаток считаются по значению, которое видит приложение
+		for k, val := range m {
+			m[k] = strings.TrimRight(val, "\r\n")
+		}
+		values, source = m, "dir:"+v.secretsDir
+	case v.secretsPrefix != "":
+		values, source = envWithPrefix(v.secretsPrefix), "env:"+v.secretsPrefix
+	default:
+		values, source = map[string]string{}, "none"
 	}
-	writeJSON(w, http.StatusOK, map[string]string{
-		"username": secretUsername,
-		"password": masked,
+
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	items := make([]map[string]string, 0, len(keys))
+	for _, k := range keys {
+		st, ok := v.masks[k]
+		if !ok {
+			st = v.maskDefault
+		}
+		items = append(items, map[string]string{
+			"key":   k,
+			"mask":  string(st),
+			"value": mask.Apply(st, values[k]),
+		})
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"source":  source,
+		"secrets": items,
 	})
 }
 
+func envWithPrefix(prefix string) map[string]string {
+	out := make(map[string]string)
+	for _, kv := range os.Environ() {
+		k, val, _ := strings.Cut(kv, "=")
+		if strings.HasPrefix(k, prefix) {
+			out[k] = val
+		}
+	}
+	return out
+}
+
 // swagger:route GET /test-env config-map testEnv
 // Returns value configured via CONFIG_MAP_ENV_VAR.
 // responses:
@@ -29,6 +96,6 @@ func Secret(w http.ResponseWriter, _ *http.Request) {
 //	200: envResponse
 func TestEnv(w http.ResponseWriter, _ *http.Request) {
 	writeJSON(w, http.StatusOK, map[string]string{
-		"configMapEnvVar": configMapVal,
+		"configMapEnvVar": live.Load().configMapVal,
 	})
 }
@@ -5,6 +5,9 @@ import (
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -14,7 +17,7 @@ import (
 )
 
 func testConfig() config.Config {
-	return config.Config{Port: "8080", ReadinessWarmupSeconds: 1, ShutdownTimeoutSeconds: 5, ConfigMapEnvVar: "test"}
+	return config.Config{Port: "8080", ReadinessWarmupSeconds: 1, ShutdownTimeoutSeconds: 5, ConfigMapEnvVar: "test", SwaggerEnabled: true}
 }
 
 func performRequest(t *testing.T, mux *http.ServeMux, method, path string) *httptest.ResponseRecorder {
@@ -51,6 +54,38 @@ func TestReadyz(t *testing.T) {
 		b, _ := io.ReadAll(rec.Body)
 		t.Fatalf("expected 200 ready, got %d body=%s", rec.Code, string(b))
 	}
+	rec := performRequest(t, mux, http.MethodGet, "/readyz?verbose")
+	var body struct {
+		Ready  string `json:"ready"`
+		Checks []struct {
+			Name   string `json:"name"`
+			Status string `json:"status"`
+		} `json:"checks"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("failed to unmarshal: %v", err)
+	}
+	if body.Ready != "true" || len(body.Checks) == 0 || body.Checks[0].Name != "warmup" {
+		t.Fatalf("unexpected verbose readyz: %s", rec.Body.String())
+	}
+}
+
+func TestStartupz(t *testing.T) {
+	cfg := testConfig()
+	cfg.ReadinessWarmupSeconds = 2
+	mux := api.NewMux(cfg)
+	handler.SetStartTime(time.Now())
+	if rec := performRequest(t, mux, http.MethodGet, "/startupz"); rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503 while warming, got %d body=%s", rec.Code, rec.Body.String())
+	}
+	handler.SetStartTime(time.Now().Add(-3 * time.Second))
+	if rec := performRequest(t, mux, http.MethodGet, "/startupz"); rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 started, got %d body=%s", rec.Code, rec.Body.String())
+	}
+	handler.SetStartTime(time.Now()) // после успешного старта probe больше не пересчитывается
+	if rec := performRequest(t, mux, http.MethodGet, "/startupz"); rec.Code != http.StatusOK {
+		t.Fatalf("expected startup to stay latched, got %d", rec.Code)
+	}
 }
 
 func TestVersion(t *testing.T) {
@@ -98,3 +133,279 @@ func TestSecret(t *testing.T) {
 		t.Fatalf("unexpected password mask: %s", body["password"])
 	}
 }
+
+func TestSecrets(t *testing.T) {
+	dir := t.TempDir()
+	for name, val := range map[string]string{"password": "password", "token": "abcdef\n", "api-key": "k3y\r\n"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(val), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cfg := testConfig()
+	cfg.SecretsDir = dir
+	cfg.SecretMaskDefault = "length"
+	cfg.SecretMasks = map[string]string{"password": "partial", "token": "sha256"}
+	mux := api.NewMux(cfg)
+	rec := performRequest(t, mux, http.MethodGet, "/secrets")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d body=%s", rec.Code, rec.Body.String())
+	}
+	var body struct {
+		Secrets []struct{ Key, Mask, Value string }
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("failed to unmarshal: %v", err)
+	}
+	got := map[string]string{}
+	for _, s := range body.Secrets {
+		got[s.Key] = s.Value
+	}
+	want := map[string]string{"api-key": "len=3", "password": "p***d", "token": "sha256:bef57ec7f53a6d40"}
+	for k, v := range want {
+		if got[k] != v {
+			t.Fatalf("unexpected mask for %s: %q (all: %+v)", k, got[k], got)
+		}
+	}
+
+	cfg.SecretsDir = filepath.Join(dir, "missing")
+	rec = performRequest(t, api.NewMux(cfg), http.MethodGet, "/secrets")
+	if rec.Code != http.StatusInternalServerError || strings.Contains(rec.Body.String(), dir) {
+		t.Fatalf("expected 500 without the directory path, got %d body=%s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestEffectiveConfig(t *testing.T) {
+	cfg := testConfig()
+	cfg.AdminToken = "admin-token"
+	cfg.Postgres = config.Postgres{Host: "db.local", Port: 5432, User: "lamarr", Pass: "qwerty12345", DB: "db"}
+	mux := api.NewMux(cfg)
+	if rec := performRequest(t, mux, http.MethodGet, "/admin/config"); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 without token, got %d", rec.Code)
+	}
+	req := httptest.NewRequest(http.MethodGet, "/admin/config", nil)
+	req.Header.Set("Authorization", "Bearer admin-token")
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d body=%s", rec.Code, rec.Body.String())
+	}
+	var body struct {
+		Config struct {
+			AdminToken string `json:"admin_token"`
+			Postgres   struct {
+				Host     string `json:"host"`
+				Password string `json:"password"`
+			} `json:"postgres"`
+		} `json:"config"`
+		Sources map[string]string `json:"sources"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("failed to unmarshal: %v", err)
+	}
+	if body.Config.Postgres.Host != "db.local" || body.Config.Postgres.Password != "***" || body.Config.AdminToken != "***" {
+		t.Fatalf("unexpected config: %+v", body.Config)
+	}
+	if body.Sources["postgres.host"] != "default" {
+		t.Fatalf("unexpected sources: %+v", body.Sources)
+	}
+}
+
+func TestDBRequestsInMemory(t *testing.T) {
+	mux := api.NewMux(testConfig()) // Postgres не сконфигурирован — хранилище в памяти
+	for i := 1; i <= 2; i++ {
+		rec := performRequest(t, mux, http.MethodPost, "/db/requests")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("insert: expected 200, got %d body=%s", rec.Code, rec.Body.String())
+		}
+		var body struct {
+			ID int64 `json:"id"`
+		}
+		if err := json.NewDecoder(rec.Body).Decode(&body); err != nil || body.ID != int64(i) {
+			t.Fatalf("insert: expected id %d, got %d (%v)", i, body.ID, err)
+		}
+	}
+
+	rec := performRequest(t, mux, http.MethodGet, "/db/requests?limit=1")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("list: expected 200, got %d body=%s", rec.Code, rec.Body.String())
+	}
+	var list struct {
+		Items []struct {
+			ID int64 `json:"id"`
+		} `json:"items"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&list); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(list.Items) != 1 || list.Items[0].ID != 2 {
+		t.Fatalf("expected latest record id=2, got %+v", list.Items)
+	}
+	if rec := performRequest(t, mux, http.MethodGet, "/db/requests?limit=0"); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for invalid limit, got %d", rec.Code)
+	}
+	if rec := performRequest(t, mux, http.MethodDelete, "/db/requests"); rec.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("expected 405, got %d", rec.Code)
+	}
+}
+
+func TestDBRequestsPostgresNotConnected(t *testing.T) {
+	cfg := testConfig()
+	cfg.Postgres = config.Postgres{Host: "db.local", Port: 5432, User: "lamarr", DB: "db"}
+	mux := api.NewMux(cfg) // менеджер БД не запущен: пул не создан
+	if rec := performRequest(t, mux, http.MethodPost, "/db/requests"); rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503 until Postgres is connected, got %d body=%s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestDBRequestsAsync(t *testing.T) {
+	cfg := testConfig()
+	cfg.RequestsWriteMode = "async"
+	cfg.RequestsBatchSize, cfg.RequestsQueueSize, cfg.RequestsBatchIntervalMs = 10, 10, 10
+	mux := api.NewMux(cfg)
+	if rec := performRequest(t, mux, http.MethodPost, "/db/requests"); rec.Code != http.StatusAccepted {
+		t.Fatalf("expected 202 queued, got %d body=%s", rec.Code, rec.Body.String())
+	}
+	if rec := performRequest(t, mux, http.MethodPost, "/db/requests?sync=true"); rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 for synchronous insert, got %d body=%s", rec.Code, rec.Body.String())
+	}
+	for range 9 { // очередь заполнена: Run не запущен
+		performRequest(t, mux, http.MethodPost, "/db/requests")
+	}
+	rec := performRequest(t, mux, http.MethodPost, "/db/requests")
+	if rec.Code != http.StatusServiceUnavailable || rec.Header().Get("Retry-After") == "" {
+		t.Fatalf("expected 503 with Retry-After on full queue, got %d", rec.Code)
+	}
+}
+
+func TestDBRequestsBatch(t *testing.T) {
+	mux := api.NewMux(testConfig())
+	post := func(body string) (int, map[string]any) {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPost, "/db/requests/batch", strings.NewReader(body))
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		var out map[string]any
+		if err := json.NewDecoder(rec.Body).Decode(&out); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		return rec.Code, out
+	}
+
+	code, out := post(`[{"createdAt":"2024-05-01T10:00:00Z"}, {}]`)
+	if code != http.StatusOK || out["count"] != 2.0 || out["firstId"] != 1.0 || out["lastId"] != 2.0 {
+		t.Fatalf("JSON array: got %d %v", code, out)
+	}
+	code, out = post("{}\n{\"createdAt\":\"2024-05-01T10:00:01Z\"}\n\n{}\n")
+	if code != http.StatusOK || out["count"] != 3.0 || out["firstId"] != 3.0 || out["lastId"] != 5.0 {
+		t.Fatalf("NDJSON: got %d %v", code, out)
+	}
+	code, out = post("{}\n{\"createdAt\":\"yesterday\"}\n")
+	if code != http.StatusBadRequest || !strings.Contains(out["error"].(string), "record 2") {
+		t.Fatalf("invalid record: got %d %v", code, out)
+	}
+	code, out = post("")
+	if code != http.StatusOK || out["count"] != 0.0 {
+		t.Fatalf("empty body: got %d %v", code, out)
+	}
+	for _, body := range []string{`[{}] garbage`, `[{}] {}`, `[{}]]`} {
+		if code, out = post(body); code != http.StatusBadRequest {
+			t.Fatalf("%q: expected 400 for data after the array, got %d %v", body, code, out)
+		}
+	}
+	if code, out = post("[{}]\n\n"); code != http.StatusOK || out["count"] != 1.0 {
+		t.Fatalf("trailing whitespace after the array: got %d %v", code, out)
+	}
+	if code, out = post("[" + strings.Repeat(" ", 64<<20)); code != http.StatusRequestEntityTooLarge { // больше maxImportBody
+		t.Fatalf("oversized body: expected 413, got %d %v", code, out)
+	}
+	rec := performRequest(t, mux, http.MethodGet, "/db/requests?limit=1000")
+	var list struct {
+		Items []json.RawMessage `json:"items"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&list); err != nil || len(list.Items) != 6 {
+		t.Fatalf("expected failed batches to insert nothing, have %d records (%v)", len(list.Items), err)
+	}
+}
+
+func TestStats(t *testing.T) {
+	mux := api.NewMux(testConfig())
+	body := `[{"createdAt":"2024-05-01T10:00:10Z","path":"/a"},{"createdAt":"2024-05-01T10:00:50Z","path":"/b"},{"createdAt":"2024-05-01T10:02:00Z","path":"/a"}]`
+	req := httptest.NewRequest(http.MethodPost, "/db/requests/batch", strings.NewReader(body))
+	mux.ServeHTTP(httptest.NewRecorder(), req)
+
+	get := func(query string) (int, []map[string]any) {
+		t.Helper()
+		rec := performRequest(t, mux, http.MethodGet, "/stats?"+query)
+		var out struct {
+			Points []map[string]any `json:"points"`
+		}
+		if err := json.NewDecoder(rec.Body).Decode(&out); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		return rec.Code, out.Points
+	}
+
+	code, points := get("from=2024-05-01T10:00:00Z&to=2024-05-01T10:03:00Z")
+	if code != http.StatusOK || len(points) != 3 {
+		t.Fatalf("expected 3 minute buckets, got %d %v", code, points)
+	}
+	for i, want := range []float64{2, 0, 1} { // пустая минута заполнена нулём
+		if points[i]["count"] != want {
+			t.Fatalf("bucket %d: expected %v, got %v", i, want, points[i])
+		}
+	}
+	code, points = get("bucket=hour&group=path&from=2024-05-01T10:00:00Z&to=2024-05-01T11:00:00Z")
+	if code != http.StatusOK || len(points) != 2 || points[0]["key"] != "/a" || points[0]["count"] != 2.0 || points[1]["count"] != 1.0 {
+		t.Fatalf("group by path: got %d %v", code, points)
+	}
+	for _, q := range []string{"bucket=day", "group=host", "from=yesterday", "from=2024-05-01T10:00:00Z&to=2024-05-01T09:00:00Z", "from=2000-01-01T00:00:00Z"} {
+		if rec := performRequest(t, mux, http.MethodGet, "/stats?"+q); rec.Code != http.StatusBadRequest {
+			t.Fatalf("%s: expected 400, got %d", q, rec.Code)
+		}
+	}
+}
+
+func TestExport(t *testing.T) {
+	mux := api.NewMux(testConfig())
+	body := `[{"createdAt":"2024-05-01T10:00:02Z","path":"/b"},{"createdAt":"2024-05-01T10:00:01Z","pod":"p1","path":"/a"},{"createdAt":"2024-05-02T00:00:00Z"}]`
+	mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/db/requests/batch", strings.NewReader(body)))
+
+	get := func(path, accept string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := get("/db/export/requests?to=2024-05-02T00:00:00Z", "")
+	lines := strings.Split(strings.TrimSpace(rec.Body.String()), "\n")
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "application/x-ndjson" || len(lines) != 2 {
+		t.Fatalf("ndjson: got %d %q %q", rec.Code, rec.Header().Get("Content-Type"), rec.Body.String())
+	}
+	var first struct {
+		ID   int64  `json:"id"`
+		Pod  string `json:"pod"`
+		Path string `json:"path"`
+	}
+	if err := json.Unmarshal([]byte(lines[0]), &first); err != nil || first.ID != 2 || first.Pod != "p1" || first.Path != "/a" {
+		t.Fatalf("expected oldest record first, got %s (%v)", lines[0], err)
+	}
+
+	rec = get("/db/export/requests?from=2024-05-01T10:00:02Z", "text/csv;q=0.9, */*")
+	want := "id,createdAt,pod,path\n1,2024-05-01T10:00:02Z,,/b\n3,2024-05-02T00:00:00Z,,/db/requests/batch\n"
+	if rec.Code != http.StatusOK || !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/csv") || rec.Body.String() != want {
+		t.Fatalf("csv: got %d %q", rec.Code, rec.Body.String())
+	}
+	if rec := get("/db/export/cron_runs?format=csv", "application/x-ndjson"); rec.Code != http.StatusOK || rec.Body.String() != "id,executedAt\n" {
+		t.Fatalf("?format must win over Accept, got %d %q", rec.Code, rec.Body.String())
+	}
+	for _, path := range []string{"/db/export/requests?format=xml", "/db/export/cron_runs?from=yesterday"} {
+		if rec := get(path, ""); rec.Code != http.StatusBadRequest {
+			t.Fatalf("%s: expected 400, got %d", path, rec.Code)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"time"
+
+	"k8s-hw/internal/db"
+)
+
+// swagger:route GET /stats db stats
+// Requests per minute or hour over [from, to) (RFC3339, default: last hour for minute buckets,
+// last day for hour buckets), optionally per pod or path. Query: bucket=minute|hour, from, to, group=pod|path.
+// responses:
+//
+//	200: statsResponse
+//	400: errorResponse
+func Stats(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	qs := r.URL.Query()
+	q := db.StatsQuery{Bucket: qs.Get("bucket"), Group: qs.Get("group"), To: time.Now().UTC()}
+	if q.Bucket == "" {
+		q.Bucket = db.BucketMinute
+	}
+	if err := queryTimes(qs, &q.From, &q.To); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	if q.From.IsZero() {
+		window := time.Hour
+		if q.Bucket == db.BucketHour {
+			window = 24 * time.Hour
+		}
+		q.From = q.To.Add(-window)
+	}
+	if err := q.Validate(); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	repo, err := repository()
+	if err != nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": err.Error()})
+		return
+	}
+	points, err := repo.Stats(r.Context(), q)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	if points == nil {
+		points = []db.StatsPoint{}
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"bucket": q.Bucket,
+		"from":   q.From,
+		"to":     q.To,
+		"group":  q.Group,
+		"points": points,
+	})
+}
+
+// queryTimes разбирает RFC3339-параметры from и to; отсутствующий параметр оставляет значение как есть.
+func queryTimes(qs url.Values, from, to *time.Time) error {
+	for _, p := range []struct {
+		name string
+		dst  *time.Time
+	}{{"from", from}, {"to", to}} {
+		v := qs.Get(p.name)
+		if v == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return errors.New(p.name + " must be RFC3339")
+		}
+		*p.dst = t.UTC()
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// DirWritable проверяет, что в каталог (PVC) можно записать и удалить файл.
+func DirWritable(dir string) func(context.Context) error {
+	return func(context.Context) error {
+		f, err := os.CreateTemp(dir, ".healthcheck-*")
+		if err != nil {
+			return fmt.Errorf("create temp file: %w", err)
+		}
+		name := f.Name()
+		_, werr := f.Write([]byte(time.Now().Format(time.RFC3339Nano)))
+		cerr := f.Close()
+		rerr := os.Remove(name)
+		for _, err := range []error{werr, cerr, rerr} {
+			if err != nil {
+				return fmt.Errorf("%s: %w", filepath.Base(name), err)
+			}
+		}
+		return nil
+	}
+}
+
+// HTTPStatus проверяет, что GET url отвечает статусом 2xx (например, Vault /v1/sys/health).
+func HTTPStatus(url string) func(context.Context) error {
+	return func(ctx context.Context) error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return err
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return err
+		}
+		_ = resp.Body.Close()
+		if resp.StatusCode < 200 || resp.StatusCode > 299 {
+			return fmt.Errorf("GET %s: status %d", url, resp.StatusCode)
+		}
+		return nil
+	}
+}
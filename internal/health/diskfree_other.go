@@ -0,0 +1,11 @@
+//go:build !(linux || darwin || freebsd)
+
+package health
+
+import "context"
+
+// DiskFree на платформах без statfs(2) ничего не проверяет: образ собирается под linux,
+// а сборка под другие ОС нужна только для локального запуска.
+func DiskFree(string, uint64) func(context.Context) error {
+	return func(context.Context) error { return nil }
+}
@@ -0,0 +1,24 @@
+//go:build linux || darwin || freebsd
+
+package health
+
+import (
+	"context"
+	"fmt"
+	"syscall"
+)
+
+// DiskFree проверяет, что на файловой системе каталога свободно не меньше minBytes.
+func DiskFree(dir string, minBytes uint64) func(context.Context) error {
+	return func(context.Context) error {
+		var st syscall.Statfs_t
+		if err := syscall.Statfs(dir, &st); err != nil {
+			return fmt.Errorf("statfs %s: %w", dir, err)
+		}
+		free := uint64(st.Bavail) * uint64(st.Bsize)
+		if free < minBytes {
+			return fmt.Errorf("only %d MiB free on %s, need %d MiB", free>>20, dir, minBytes>>20)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,323 @@
+// Package health — реестр проверок состояния для /healthz и /readyz.
+//
+// Компоненты регистрируют именованные проверки для нужного probe с признаком критичности,
+// таймаутом и временем кэширования результата. Некритичная проверка попадает в отчёт,
+// но не переводит probe в состояние fail.
+//
+// Проверки внешних зависимостей помечаются Background: их выполняет фоновый цикл
+// (RunBackground), а probe лишь читает последнее состояние. Так всплеск запросов от kubelet
+// не нагружает БД, а медленная БД не приводит к таймауту самого probe. Для фоновых проверок
+// действует гистерезис: состояние меняется только после N провалов / M успехов подряд.
+package health
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"k8s-hw/internal/watchdog"
+)
+
+// Probe — вид проверки Kubernetes.
+type Probe string
+
+const (
+	Liveness  Probe = "liveness"
+	Readiness Probe = "readiness"
+	Startup   Probe = "startup"
+)
+
+// Статусы отдельной проверки и отчёта в целом.
+const (
+	StatusOK   = "ok"
+	StatusFail = "fail"
+	// StatusDegraded — критичные проверки прошли, но провалены некритичные (optional) зависимости.
+	StatusDegraded = "degraded"
+)
+
+// defaultTimeout применяется, если у проверки не задан Timeout.
+const defaultTimeout = time.Second
+
+var errPending = errors.New("not checked yet")
+
+// Check описывает одну проверку.
+type Check struct {
+	Name       string
+	Critical   bool          // провал критичной проверки переводит probe в fail
+	Timeout    time.Duration // ограничение на один запуск Fn
+	CacheTTL   time.Duration // результат переиспользуется в течение TTL (0 — без кэша), только для не-Background
+	Background bool          // выполняется фоновым циклом, probe читает сохранённое состояние
+	Fn         func(ctx context.Context) error
+}
+
+// Result — результат одной проверки.
+type Result struct {
+	Name      string    `json:"name"`
+	Status    string    `json:"status"`
+	Critical  bool      `json:"critical"`
+	Error     string    `json:"error,omitempty"`
+	LatencyMs float64   `json:"latencyMs"`
+	CheckedAt time.Time `json:"checkedAt"`
+	Cached    bool      `json:"cached,omitempty"`
+	// Failures — число провалов подряд (для фоновых проверок может быть > 0 при Status ok,
+	// пока не достигнут порог гистерезиса).
+	Failures int `json:"consecutiveFailures,omitempty"`
+}
+
+// Report — сводный результат probe.
+type Report struct {
+	Status   string   `json:"status"`
+	Failed   []string `json:"failed,omitempty"`   // имена проваленных критичных проверок
+	Degraded []string `json:"degraded,omitempty"` // имена проваленных некритичных проверок
+	Checks   []Result `json:"checks"`
+}
+
+// OK сообщает, прошёл ли probe (в том числе в деградированном режиме).
+func (r Report) OK() bool { return r.Status != StatusFail }
+
+type entry struct {
+	check Check
+	mu    sync.Mutex
+	last  Result
+	valid bool
+	// состояние гистерезиса фоновой проверки
+	healthy   bool
+	failures  int
+	successes int
+}
+
+// Registry хранит проверки по probe. Безопасен для конкурентного использования.
+type Registry struct {
+	mu           sync.RWMutex
+	checks       map[Probe][]*entry
+	failAfter    int
+	recoverAfter int
+}
+
+// NewRegistry создаёт пустой реестр (без гистерезиса: пороги 1/1).
+func NewRegistry() *Registry {
+	return &Registry{checks: make(map[Probe][]*entry), failAfter: 1, recoverAfter: 1}
+}
+
+// SetHysteresis задаёт пороги для фоновых проверок: failures провалов подряд переводят
+// проверку в fail, successes успехов подряд — обратно в ok.
+func (r *Registry) SetHysteresis(failures, successes int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.failAfter, r.recoverAfter = max(failures, 1), max(successes, 1)
+}
+
+// Register добавляет проверку к probe. Повторная регистрация с тем же именем заменяет проверку.
+func (r *Registry) Register(p Probe, c Check) {
+	if c.Timeout <= 0 {
+		c.Timeout = defaultTimeout
+	}
+	e := &entry{check: c}
+	if c.Background {
+		e.last = Result{Name: c.Name, Status: StatusFail, Critical: c.Critical, Error: errPending.Error()}
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, old := range r.checks[p] {
+		if old.check.Name == c.Name {
+			r.checks[p][i] = e
+			return
+		}
+	}
+	r.checks[p] = append(r.checks[p], e)
+}
+
+// Unregister удаляет проверку (например, когда зависимость отключили при hot reload).
+func (r *Registry) Unregister(p Probe, name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	list := r.checks[p]
+	for i, e := range list {
+		if e.check.Name == name {
+			r.checks[p] = append(list[:i:i], list[i+1:]...)
+			return
+		}
+	}
+}
+
+// Run собирает отчёт probe в порядке регистрации. Обычные проверки выполняются
+// параллельно (с учётом кэша), для фоновых берётся последнее состояние.
+func (r *Registry) Run(ctx context.Context, p Probe) Report {
+	r.mu.RLock()
+	entries := append([]*entry(nil), r.checks[p]...)
+	r.mu.RUnlock()
+
+	results := make([]Result, len(entries))
+	var wg sync.WaitGroup
+	for i, e := range entries {
+		if e.check.Background {
+			results[i] = e.snapshot()
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = e.run(ctx)
+		}()
+	}
+	wg.Wait()
+
+	rep := Report{Status: StatusOK, Checks: results}
+	for _, res := range results {
+		switch {
+		case res.Status == StatusOK:
+		case res.Critical:
+			rep.Failed = append(rep.Failed, res.Name)
+		default:
+			rep.Degraded = append(rep.Degraded, res.Name)
+		}
+	}
+	if len(rep.Failed) > 0 {
+		rep.Status = StatusFail
+	} else if len(rep.Degraded) > 0 {
+		rep.Status = StatusDegraded
+	}
+	return rep
+}
+
+// Healthy сообщает последнее известное состояние проверки name в probe p без её запуска
+// (для фоновых — с учётом гистерезиса). Незарегистрированная проверка считается здоровой,
+// ещё не выполнявшаяся — нет.
+func (r *Registry) Healthy(p Probe, name string) bool {
+	r.mu.RLock()
+	var found *entry
+	for _, e := range r.checks[p] {
+		if e.check.Name == name {
+			found = e
+		}
+	}
+	r.mu.RUnlock()
+	if found == nil {
+		return true
+	}
+	return found.snapshot().Status == StatusOK
+}
+
+// Observed сообщает, что каждая фоновая проверка выполнилась хотя бы раз.
+func (r *Registry) Observed() bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, list := range r.checks {
+		for _, e := range list {
+			if e.check.Background && !e.observed() {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func (e *entry) observed() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.valid
+}
+
+// RunBackground сразу и затем каждые interval выполняет все фоновые проверки.
+// После каждого прохода отмечает heartbeat (watchdog.Beat). Блокируется до отмены ctx.
+func (r *Registry) RunBackground(ctx context.Context, interval time.Duration) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		r.tick(ctx)
+		watchdog.Beat(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
+
+func (r *Registry) tick(ctx context.Context) {
+	r.mu.RLock()
+	var entries []*entry
+	for _, list := range r.checks {
+		for _, e := range list {
+			if e.check.Background {
+				entries = append(entries, e)
+			}
+		}
+	}
+	failAfter, recoverAfter := r.failAfter, r.recoverAfter
+	r.mu.RUnlock()
+
+	var wg sync.WaitGroup
+	for _, e := range entries {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			res := e.execute(ctx)
+			e.observe(res, failAfter, recoverAfter)
+		}()
+	}
+	wg.Wait()
+}
+
+func (e *entry) snapshot() Result {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.last
+}
+
+// observe применяет гистерезис к результату фоновой проверки.
+func (e *entry) observe(res Result, failAfter, recoverAfter int) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if res.Status == StatusOK {
+		e.failures, e.successes = 0, e.successes+1
+		if !e.healthy && e.successes >= recoverAfter {
+			e.healthy = true
+		}
+	} else {
+		e.failures, e.successes = e.failures+1, 0
+		if e.healthy && e.failures >= failAfter {
+			e.healthy = false
+		}
+	}
+	res.Failures = e.failures
+	res.Status = StatusFail
+	if e.healthy {
+		res.Status = StatusOK
+	} else if res.Error == "" {
+		res.Error = "recovering, waiting for consecutive successes"
+	}
+	e.last, e.valid = res, true
+}
+
+func (e *entry) run(ctx context.Context) Result {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.valid && e.check.CacheTTL > 0 && time.Since(e.last.CheckedAt) < e.check.CacheTTL {
+		res := e.last
+		res.Cached = true
+		return res
+	}
+	e.last, e.valid = e.execute(ctx), true
+	return e.last
+}
+
+func (e *entry) execute(ctx context.Context) Result {
+	cctx, cancel := context.WithTimeout(ctx, e.check.Timeout)
+	defer cancel()
+	start := time.Now()
+	err := e.check.Fn(cctx)
+	res := Result{
+		Name:      e.check.Name,
+		Status:    StatusOK,
+		Critical:  e.check.Critical,
+		LatencyMs: float64(time.Since(start).Microseconds()) / 1000,
+		CheckedAt: start,
+	}
+	if err != nil {
+		res.Status = StatusFail
+		res.Error = err.Error()
+	}
+	return res
+}
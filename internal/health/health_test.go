@@ -0,0 +1,73 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRegistryCriticality(t *testing.T) {
+	r := NewRegistry()
+	r.Register(Readiness, Check{Name: "db", Critical: true, Fn: func(context.Context) error { return nil }})
+	r.Register(Readiness, Check{Name: "vault", Fn: func(context.Context) error { return errors.New("sealed") }})
+	rep := r.Run(context.Background(), Readiness)
+	if !rep.OK() || rep.Status != StatusDegraded || len(rep.Degraded) != 1 || rep.Checks[1].Status != StatusFail {
+		t.Fatalf("non-critical failure must only degrade probe: %+v", rep)
+	}
+	r.Register(Readiness, Check{Name: "db", Critical: true, Fn: func(context.Context) error { return errors.New("down") }})
+	rep = r.Run(context.Background(), Readiness)
+	if rep.OK() || len(rep.Failed) != 1 || rep.Failed[0] != "db" {
+		t.Fatalf("critical failure must fail probe: %+v", rep)
+	}
+}
+
+func TestRegistryCacheAndTimeout(t *testing.T) {
+	r := NewRegistry()
+	calls := 0
+	r.Register(Liveness, Check{Name: "cached", CacheTTL: time.Minute, Fn: func(context.Context) error { calls++; return nil }})
+	r.Register(Liveness, Check{Name: "slow", Critical: true, Timeout: 10 * time.Millisecond, Fn: func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}})
+	r.Run(context.Background(), Liveness)
+	rep := r.Run(context.Background(), Liveness)
+	if calls != 1 || !rep.Checks[0].Cached {
+		t.Fatalf("expected cached result, calls=%d rep=%+v", calls, rep)
+	}
+	if rep.OK() || rep.Checks[1].Error == "" {
+		t.Fatalf("expected slow check to time out: %+v", rep)
+	}
+}
+
+func TestBackgroundHysteresis(t *testing.T) {
+	r := NewRegistry()
+	r.SetHysteresis(2, 2)
+	var fail bool
+	r.Register(Readiness, Check{Name: "db", Critical: true, Background: true, Fn: func(context.Context) error {
+		if fail {
+			return errors.New("down")
+		}
+		return nil
+	}})
+	ctx := context.Background()
+	expect := func(step string, ok bool) {
+		t.Helper()
+		if rep := r.Run(ctx, Readiness); rep.OK() != ok {
+			t.Fatalf("%s: expected ok=%v, got %+v", step, ok, rep)
+		}
+	}
+	expect("before first run", false)
+	r.tick(ctx)
+	expect("one success of two", false)
+	r.tick(ctx)
+	expect("recovered", true)
+	fail = true
+	r.tick(ctx)
+	expect("one failure of two", true)
+	r.tick(ctx)
+	expect("failed", false)
+	fail = false
+	r.tick(ctx)
+	expect("one success after failure", false)
+}
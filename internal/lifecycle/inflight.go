@@ -0,0 +1,65 @@
+// Package lifecycle — учёт выполняющихся запросов и фоновых воркеров для упорядоченного
+// graceful shutdown.
+package lifecycle
+
+import (
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Request — запрос, который ещё обрабатывается.
+type Request struct {
+	Method string    `json:"method"`
+	Path   string    `json:"path"`
+	Since  time.Time `json:"since"`
+}
+
+// Tracker считает HTTP-запросы в обработке.
+type Tracker struct {
+	mu     sync.Mutex
+	next   uint64
+	active map[uint64]Request
+}
+
+// NewTracker создаёт пустой Tracker.
+func NewTracker() *Tracker {
+	return &Tracker{active: make(map[uint64]Request)}
+}
+
+// Middleware регистрирует запрос на время его обработки.
+func (t *Tracker) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.mu.Lock()
+		t.next++
+		id := t.next
+		t.active[id] = Request{Method: r.Method, Path: r.URL.Path, Since: time.Now()}
+		t.mu.Unlock()
+		defer func() {
+			t.mu.Lock()
+			delete(t.active, id)
+			t.mu.Unlock()
+		}()
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Count возвращает число запросов в обработке.
+func (t *Tracker) Count() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return len(t.active)
+}
+
+// Snapshot возвращает запросы в обработке, самые старые первыми.
+func (t *Tracker) Snapshot() []Request {
+	t.mu.Lock()
+	out := make([]Request, 0, len(t.active))
+	for _, r := range t.active {
+		out = append(out, r)
+	}
+	t.mu.Unlock()
+	sort.Slice(out, func(i, j int) bool { return out[i].Since.Before(out[j].Since) })
+	return out
+}
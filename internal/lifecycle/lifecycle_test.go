@@ -0,0 +1,87 @@
+package lifecycle
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestTrackerCountsInFlight(t *testing.T) {
+	tr := NewTracker()
+	release := make(chan struct{})
+	entered := make(chan struct{})
+	h := tr.Middleware(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		close(entered)
+		<-release
+	}))
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/db/requests", nil))
+	}()
+	<-entered
+	if snap := tr.Snapshot(); tr.Count() != 1 || snap[0].Path != "/db/requests" {
+		t.Fatalf("unexpected in-flight state: %+v", snap)
+	}
+	close(release)
+	wg.Wait()
+	if tr.Count() != 0 {
+		t.Fatalf("expected no in-flight requests, got %d", tr.Count())
+	}
+}
+
+func TestGroupShutdownOrderAndTimeout(t *testing.T) {
+	var g Group
+	g.Go("ok", func(ctx context.Context) { <-ctx.Done() })
+	g.Go("stuck", func(context.Context) { time.Sleep(time.Second) })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err := g.Shutdown(ctx)
+	if err == nil || !strings.Contains(err.Error(), "stuck") {
+		t.Fatalf("expected stuck worker to be reported, got %v", err)
+	}
+
+	var g2 Group
+	order := make(chan string, 2)
+	for _, name := range []string{"first", "second"} {
+		g2.Go(name, func(ctx context.Context) { <-ctx.Done(); order <- name })
+	}
+	if err := g2.Shutdown(context.Background()); err != nil {
+		t.Fatalf("shutdown: %v", err)
+	}
+	if a, b := <-order, <-order; a != "second" || b != "first" {
+		t.Fatalf("expected reverse start order, got %s, %s", a, b)
+	}
+}
+
+func TestStopContextCarriesShutdownDeadline(t *testing.T) {
+	var g Group
+	got := make(chan context.Context, 1)
+	started := make(chan struct{})
+	g.Go("flusher", func(ctx context.Context) {
+		ctx, cancel := context.WithCancel(ctx) // производный контекст, как у watchdog
+		defer cancel()
+		if _, ok := StopContext(ctx).Deadline(); ok {
+			t.Error("stop context must be empty before Shutdown")
+		}
+		close(started)
+		<-ctx.Done()
+		got <- StopContext(ctx)
+	})
+	<-started
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	if err := g.Shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+	stop := <-got
+	if d, ok := stop.Deadline(); !ok || stop.Err() != nil {
+		t.Fatalf("expected live shutdown context with deadline, got deadline=%v err=%v", d, stop.Err())
+	}
+}
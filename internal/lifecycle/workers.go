@@ -0,0 +1,86 @@
+package lifecycle
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+type worker struct {
+	name   string
+	cancel context.CancelFunc
+	done   chan struct{}
+	stop   atomic.Pointer[context.Context] // контекст Shutdown, задаётся до cancel
+}
+
+type workerKey struct{}
+
+// Group запускает именованные фоновые воркеры, каждый со своим контекстом, и
+// останавливает их по очереди — в порядке, обратном запуску.
+type Group struct {
+	mu      sync.Mutex
+	workers []*worker
+}
+
+// Go запускает fn в отдельной горутине. Контекст fn отменяется в Shutdown.
+func (g *Group) Go(name string, fn func(ctx context.Context)) {
+	w := &worker{name: name, done: make(chan struct{})}
+	ctx, cancel := context.WithCancel(context.WithValue(context.Background(), workerKey{}, w))
+	w.cancel = cancel
+	g.mu.Lock()
+	g.workers = append(g.workers, w)
+	g.mu.Unlock()
+	go func() {
+		defer close(w.done)
+		fn(ctx)
+	}()
+}
+
+// Shutdown останавливает воркеры в обратном порядке запуска, дожидаясь каждого.
+// Если ctx истёк, оставшиеся воркеры отменяются без ожидания, а их имена
+// логируются и возвращаются в ошибке.
+func (g *Group) Shutdown(ctx context.Context) error {
+	g.mu.Lock()
+	workers := append([]*worker(nil), g.workers...)
+	g.workers = nil
+	g.mu.Unlock()
+
+	for i := len(workers) - 1; i >= 0; i-- {
+		w := workers[i]
+		w.stop.Store(&ctx)
+		w.cancel()
+		select {
+		case <-w.done:
+			log.Printf("worker %s stopped", w.name)
+		case <-ctx.Done():
+			var running []string
+			for _, rest := range workers[:i+1] {
+				rest.stop.Store(&ctx)
+				rest.cancel()
+				select {
+				case <-rest.done:
+				default:
+					running = append(running, rest.name)
+				}
+			}
+			log.Printf("WARN: shutdown deadline exceeded, workers still running: %s", strings.Join(running, ", "))
+			return fmt.Errorf("workers still running: %s", strings.Join(running, ", "))
+		}
+	}
+	return nil
+}
+
+// StopContext возвращает контекст Shutdown, остановившего воркер (ctx — контекст воркера или
+// производный от него): в нём воркер доделывает работу после отмены в пределах общего дедлайна
+// остановки. Вне Group или до Shutdown — context.Background().
+func StopContext(ctx context.Context) context.Context {
+	if w, ok := ctx.Value(workerKey{}).(*worker); ok {
+		if stop := w.stop.Load(); stop != nil {
+			return *stop
+		}
+	}
+	return context.Background()
+}
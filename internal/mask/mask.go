@@ -0,0 +1,54 @@
+// Package mask содержит стратегии маскирования секретов для отдачи наружу.
+package mask
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// Strategy — способ маскирования значения.
+type Strategy string
+
+const (
+	// Full полностью скрывает значение.
+	Full Strategy = "full"
+	// Partial оставляет первый и последний символ: p***d.
+	Partial Strategy = "partial"
+	// Length показывает только длину значения.
+	Length Strategy = "length"
+	// SHA256 отдаёт отпечаток значения — позволяет сравнить версии секрета между подами, не раскрывая его.
+	SHA256 Strategy = "sha256"
+)
+
+// Parse проверяет имя стратегии (регистр не важен).
+func Parse(s string) (Strategy, error) {
+	st := Strategy(strings.ToLower(strings.TrimSpace(s)))
+	switch st {
+	case Full, Partial, Length, SHA256:
+		return st, nil
+	}
+	return "", fmt.Errorf("unknown mask strategy %q (want full, partial, length or sha256)", s)
+}
+
+// Apply маскирует v. Неизвестная стратегия трактуется как Full.
+func Apply(st Strategy, v string) string {
+	if v == "" {
+		return ""
+	}
+	switch st {
+	case Partial:
+		if len(v) <= 3 {
+			return "***"
+		}
+		return v[:1] + "***" + v[len(v)-1:]
+	case Length:
+		return fmt.Sprintf("len=%d", len(v))
+	case SHA256:
+		sum := sha256.Sum256([]byte(v))
+		return "sha256:" + hex.EncodeToString(sum[:8])
+	default:
+		return "***"
+	}
+}
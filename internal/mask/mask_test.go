@@ -0,0 +1,33 @@
+package mask
+
+import "testing"
+
+func TestApply(t *testing.T) {
+	cases := []struct {
+		st   Strategy
+		in   string
+		want string
+	}{
+		{Full, "password", "***"},
+		{Partial, "password", "p***d"},
+		{Partial, "abc", "***"},
+		{Length, "password", "len=8"},
+		{SHA256, "password", "sha256:5e884898da280471"},
+		{Strategy("bogus"), "password", "***"},
+		{SHA256, "", ""},
+	}
+	for _, c := range cases {
+		if got := Apply(c.st, c.in); got != c.want {
+			t.Errorf("Apply(%s, %q) = %q, want %q", c.st, c.in, got, c.want)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	if st, err := Parse(" SHA256 "); err != nil || st != SHA256 {
+		t.Fatalf("unexpected parse result %q, %v", st, err)
+	}
+	if _, err := Parse("md5"); err == nil {
+		t.Fatalf("expected error for unknown strategy")
+	}
+}
@@ -0,0 +1,121 @@
+// Package watch отслеживает изменения файлов и каталогов, смонтированных из ConfigMap/Secret.
+//
+// Kubelet обновляет такие тома атомарно: содержимое пишется в новый каталог ..<timestamp>,
+// после чего симлинк ..data переключается на него. Поэтому для каталога достаточно следить
+// за целью ..data, а для отдельных файлов — за размером и mtime (через симлинк).
+// Используется опрос, т.к. inotify не видит подмену симлинка внутри смонтированного тома.
+package watch
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"k8s-hw/internal/watchdog"
+)
+
+// Poll опрашивает paths с интервалом interval и вызывает onChange, когда отпечаток
+// хотя бы одного пути изменился. На каждом опросе отмечает heartbeat (watchdog.Beat).
+// Блокируется до отмены ctx.
+func Poll(ctx context.Context, interval time.Duration, paths []string, onChange func()) {
+	PollPaths(ctx, interval, func() []string { return paths }, onChange)
+}
+
+// PollPaths — как Poll, но список путей запрашивается у paths на каждом опросе (пути могут
+// поменяться при hot reload). Смена самого списка onChange не вызывает: отпечаток новых путей
+// становится исходным.
+func PollPaths(ctx context.Context, interval time.Duration, paths func() []string, onChange func()) {
+	cur := paths()
+	prevPaths, prev := strings.Join(cur, "\x00"), fingerprint(cur)
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			watchdog.Beat(ctx)
+			cur := paths()
+			fp := fingerprint(cur)
+			if key := strings.Join(cur, "\x00"); key != prevPaths {
+				prevPaths, prev = key, fp
+				continue
+			}
+			if fp != prev {
+				prev = fp
+				onChange()
+			}
+		}
+	}
+}
+
+func fingerprint(paths []string) string {
+	var b strings.Builder
+	for _, p := range paths {
+		b.WriteString(p)
+		b.WriteByte('=')
+		b.WriteString(pathFingerprint(p))
+		b.WriteByte(';')
+	}
+	return b.String()
+}
+
+func pathFingerprint(p string) string {
+	info, err := os.Stat(p)
+	if err != nil {
+		return "missing"
+	}
+	if !info.IsDir() {
+		return fileStamp(info)
+	}
+	if target, err := os.Readlink(filepath.Join(p, "..data")); err == nil {
+		return "data:" + target
+	}
+	// обычный каталог (например, локальный запуск без kubelet)
+	entries, err := os.ReadDir(p)
+	if err != nil {
+		return "unreadable"
+	}
+	var parts []string
+	for _, e := range entries {
+		if fi, err := os.Stat(filepath.Join(p, e.Name())); err == nil && !fi.IsDir() {
+			parts = append(parts, e.Name()+":"+fileStamp(fi))
+		}
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, ",")
+}
+
+func fileStamp(info os.FileInfo) string {
+	return fmt.Sprintf("%d/%d", info.Size(), info.ModTime().UnixNano())
+}
+
+// ReadDir читает все видимые файлы каталога (ключи ConfigMap/Secret) в map имя -> содержимое.
+// Служебные записи kubelet (..data, ..2024_01_01_...) и подкаталоги пропускаются.
+func ReadDir(dir string) (map[string]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	out := make(map[string]string, len(entries))
+	for _, e := range entries {
+		if strings.HasPrefix(e.Name(), ".") {
+			continue
+		}
+		full := filepath.Join(dir, e.Name())
+		fi, err := os.Stat(full) // ключи — симлинки на ..data/<key>
+		if err != nil || fi.IsDir() {
+			continue
+		}
+		b, err := os.ReadFile(full)
+		if err != nil {
+			return nil, fmt.Errorf("read %s: %w", full, err)
+		}
+		out[e.Name()] = string(b)
+	}
+	return out, nil
+}
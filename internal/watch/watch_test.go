@@ -0,0 +1,44 @@
+package watch
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestPollPathsFollowsCurrentPaths(t *testing.T) {
+	dir := t.TempDir()
+	oldFile, newFile := filepath.Join(dir, "old.crt"), filepath.Join(dir, "new.crt")
+	for _, f := range []string{oldFile, newFile} {
+		if err := os.WriteFile(f, []byte("v1"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var current atomic.Value
+	current.Store([]string{oldFile})
+	changes := make(chan struct{}, 10)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go PollPaths(ctx, 10*time.Millisecond, func() []string { return current.Load().([]string) }, func() { changes <- struct{}{} })
+
+	current.Store([]string{newFile}) // hot reload сменил путь: сам по себе не изменение
+	time.Sleep(50 * time.Millisecond)
+	if err := os.WriteFile(oldFile, []byte("v2-old"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if n := len(changes); n != 0 {
+		t.Fatalf("old path or path switch reported as change: %d", n)
+	}
+	if err := os.WriteFile(newFile, []byte("v2-new"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-changes:
+	case <-time.After(2 * time.Second):
+		t.Fatal("change of the new path not detected")
+	}
+}
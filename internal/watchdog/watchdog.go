@@ -0,0 +1,174 @@
+// Package watchdog делает liveness осмысленной: собственная горутина-heartbeat и
+// heartbeat'ы компонентов (фоновые воркеры, менеджер БД) отмечаются регулярно, а
+// проверка Check падает, если кто-то из них не отмечался дольше порога.
+//
+// Компоненту heartbeat передаётся через контекст (Attach), а её цикл на каждой итерации
+// вызывает Beat(ctx) — так пакеты с циклами не зависят от конкретного Watchdog.
+package watchdog
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime/pprof"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Dump — куда сохранять стеки горутин при обнаружении зависания.
+const (
+	DumpNone = "none"
+	DumpLog  = "log"
+	DumpFile = "file"
+)
+
+// selfName — heartbeat собственной горутины Watchdog.
+const selfName = "watchdog"
+
+type component struct {
+	threshold time.Duration
+	last      time.Time
+}
+
+// Watchdog отслеживает heartbeat'ы компонентов.
+type Watchdog struct {
+	mu         sync.Mutex
+	components map[string]*component
+	threshold  time.Duration
+	dump       string
+	dumpDir    string
+	dumped     bool // стеки уже сохранены в текущем эпизоде зависания
+	now        func() time.Time
+}
+
+// New создаёт Watchdog с порогом по умолчанию threshold. dump — DumpNone, DumpLog или
+// DumpFile (файл goroutines-<время>.txt в dumpDir).
+func New(threshold time.Duration, dump, dumpDir string) *Watchdog {
+	return &Watchdog{
+		components: make(map[string]*component),
+		threshold:  threshold,
+		dump:       dump,
+		dumpDir:    dumpDir,
+		now:        time.Now,
+	}
+}
+
+// Heartbeat — отметка жизни одного компонента.
+type Heartbeat struct {
+	w    *Watchdog
+	name string
+}
+
+// Register добавляет компонент; threshold <= 0 означает порог Watchdog по умолчанию.
+// Компонент считается живым с момента регистрации.
+func (w *Watchdog) Register(name string, threshold time.Duration) *Heartbeat {
+	if threshold <= 0 {
+		threshold = w.threshold
+	}
+	w.mu.Lock()
+	w.components[name] = &component{threshold: threshold, last: w.now()}
+	w.mu.Unlock()
+	return &Heartbeat{w: w, name: name}
+}
+
+// Unregister убирает компонент (например, воркер штатно завершился).
+func (w *Watchdog) Unregister(name string) {
+	w.mu.Lock()
+	delete(w.components, name)
+	w.mu.Unlock()
+}
+
+// Beat отмечает, что компонент жив.
+func (h *Heartbeat) Beat() {
+	if h == nil {
+		return
+	}
+	h.w.mu.Lock()
+	if c, ok := h.w.components[h.name]; ok {
+		c.last = h.w.now()
+	}
+	h.w.mu.Unlock()
+}
+
+type ctxKey struct{}
+
+// Attach регистрирует компонент name и возвращает контекст, через который его цикл
+// отмечается вызовом Beat(ctx).
+func (w *Watchdog) Attach(ctx context.Context, name string, threshold time.Duration) context.Context {
+	return context.WithValue(ctx, ctxKey{}, w.Register(name, threshold))
+}
+
+// Beat отмечает heartbeat компонента, привязанного к ctx через Attach (иначе ничего не делает).
+func Beat(ctx context.Context) {
+	if h, ok := ctx.Value(ctxKey{}).(*Heartbeat); ok {
+		h.Beat()
+	}
+}
+
+// Run — собственная горутина-heartbeat: если планировщик или процесс «залип»,
+// она перестанет отмечаться. Блокируется до отмены ctx.
+func (w *Watchdog) Run(ctx context.Context, interval time.Duration) {
+	hb := w.Register(selfName, 0)
+	defer w.Unregister(selfName)
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			hb.Beat()
+		}
+	}
+}
+
+// Check возвращает ошибку со списком компонентов, не отмечавшихся дольше порога.
+// При первом обнаружении зависания сохраняет стеки горутин согласно настройке dump.
+func (w *Watchdog) Check(context.Context) error {
+	w.mu.Lock()
+	now := w.now()
+	var stalled []string
+	for name, c := range w.components {
+		if age := now.Sub(c.last); age > c.threshold {
+			stalled = append(stalled, fmt.Sprintf("%s (no heartbeat for %s)", name, age.Round(time.Second)))
+		}
+	}
+	first := len(stalled) > 0 && !w.dumped
+	w.dumped = len(stalled) > 0
+	w.mu.Unlock()
+
+	if len(stalled) == 0 {
+		return nil
+	}
+	sort.Strings(stalled)
+	msg := "stalled: " + strings.Join(stalled, ", ")
+	if first {
+		w.dumpStacks(msg)
+	}
+	return fmt.Errorf("%s", msg)
+}
+
+func (w *Watchdog) dumpStacks(reason string) {
+	switch w.dump {
+	case DumpLog:
+		var b strings.Builder
+		_ = pprof.Lookup("goroutine").WriteTo(&b, 2)
+		log.Printf("WATCHDOG %s, goroutine dump:\n%s", reason, b.String())
+	case DumpFile:
+		path := filepath.Join(w.dumpDir, fmt.Sprintf("goroutines-%s.txt", w.now().UTC().Format("20060102T150405Z")))
+		f, err := os.Create(path)
+		if err != nil {
+			log.Printf("WATCHDOG %s, goroutine dump failed: %v", reason, err)
+			return
+		}
+		defer f.Close()
+		_ = pprof.Lookup("goroutine").WriteTo(f, 2)
+		log.Printf("WATCHDOG %s, goroutine dump written to %s", reason, path)
+	default:
+		log.Printf("WATCHDOG %s", reason)
+	}
+}
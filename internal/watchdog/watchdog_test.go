@@ -0,0 +1,41 @@
+package watchdog
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCheckDetectsStall(t *testing.T) {
+	dir := t.TempDir()
+	w := New(10*time.Second, DumpFile, dir)
+	now := time.Unix(1000, 0)
+	w.now = func() time.Time { return now }
+
+	ctx := w.Attach(context.Background(), "db-manager", 5*time.Second)
+	w.Register("health-checks", 0)
+	if err := w.Check(ctx); err != nil {
+		t.Fatalf("expected healthy, got %v", err)
+	}
+
+	now = now.Add(8 * time.Second)
+	err := w.Check(ctx)
+	if err == nil || !strings.Contains(err.Error(), "db-manager") || strings.Contains(err.Error(), "health-checks") {
+		t.Fatalf("expected only db-manager stalled, got %v", err)
+	}
+	dumps, _ := filepath.Glob(filepath.Join(dir, "goroutines-*.txt"))
+	if len(dumps) != 1 {
+		t.Fatalf("expected one goroutine dump, got %v", dumps)
+	}
+	if b, _ := os.ReadFile(dumps[0]); !strings.Contains(string(b), "goroutine") {
+		t.Fatalf("dump does not look like a goroutine profile")
+	}
+
+	Beat(ctx)
+	if err := w.Check(ctx); err != nil {
+		t.Fatalf("expected recovery after heartbeat, got %v", err)
+	}
+}
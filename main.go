@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"errors"
+	"flag"
 	"fmt"
 	"log"
 	"net/http"
@@ -15,38 +16,110 @@ import (
 	"k8s-hw/internal/config"
 	"k8s-hw/internal/db"
 	"k8s-hw/internal/handler"
+	"k8s-hw/internal/lifecycle"
+	"k8s-hw/internal/watch"
 )
 
+// subcommands — `app <name> ...`; без подкоманды запускается сервер.
+var subcommands = map[string]func(args []string) error{
+	"config-docs": runConfigDocs,
+	"backup":      runBackup,
+	"restore":     runRestore,
+}
+
 func main() {
-	cfg, err := config.Load()
+	if len(os.Args) > 1 {
+		if run, ok := subcommands[os.Args[1]]; ok {
+			if err := run(os.Args[2:]); err != nil && !errors.Is(err, flag.ErrHelp) && !errors.Is(err, config.ErrConfigPrinted) {
+				log.Fatalf("%s: %v", os.Args[1], err)
+			}
+			return
+		}
+	}
+
+	base, err := config.LoadArgs(os.Args[1:])
+	if errors.Is(err, config.ErrConfigPrinted) || errors.Is(err, flag.ErrHelp) {
+		return
+	}
 	if err != nil {
 		log.Fatalf("config load error: %v", err)
 	}
-	addr := fmt.Sprintf(":%s", cfg.Port)
-	log.Printf("Starting server on %s warmup=%s shutdownTimeout=%s", addr, cfg.ReadinessWarmup(), cfg.ShutdownTimeout())
-
-	// Init DB client (optional: only if required fields заданы)
-	var pgClient *db.Client
-	if cfg.Postgres.User == "" || cfg.Postgres.DB == "" { // считаем не настроенным
-		log.Println("Postgres not configured (APP_POSTGRES_USER/DB empty) — DB features disabled")
-	} else {
-		ctx, cancelDB := context.WithTimeout(context.Background(), 10*time.Second)
-		pgClient, err = db.New(ctx, cfg.Postgres)
-		cancelDB()
-		if err != nil {
-			log.Printf("WARN: failed to init postgres (DB features disabled): %v", err)
-		} else {
-			handler.SetDB(pgClient)
-			log.Printf("Postgres client initialized host=%s db=%s", cfg.Postgres.Host, cfg.Postgres.DB)
-		}
+	cfg, err := base.ApplyDirs()
+	if err != nil {
+		log.Fatalf("config dirs load error: %v", err)
 	}
+	addr := fmt.Sprintf(":%s", cfg.Port)
+	log.Printf("Starting server on %s profile=%s warmup=%s shutdownTimeout=%s", addr, cfg.Profile, cfg.ReadinessWarmup(), cfg.ShutdownTimeout())
 
 	mux := api.NewMux(cfg)
-	srv := &http.Server{Addr: addr, Handler: mux}
+	if !cfg.Postgres.Enabled() {
+		log.Println("Postgres not configured (APP_POSTGRES_DSN and APP_POSTGRES_USER/DB empty) — using in-memory storage, data is lost on restart")
+	}
+	srv := &http.Server{Addr: addr, Handler: handler.TrackInFlight(mux)}
 
 	ctxShutdown, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM, syscall.SIGINT)
 	defer stop()
 
+	// фоновые воркеры живут дольше ctxShutdown: их останавливаем уже после HTTP-сервера
+	var workers lifecycle.Group
+	// heartbeat подключает воркер к watchdog: зависший цикл валит /healthz и под перезапускается
+	wd := handler.Watchdog()
+	heartbeat := func(ctx context.Context, name string, interval time.Duration) context.Context {
+		if wd == nil {
+			return ctx
+		}
+		return wd.Attach(ctx, name, max(cfg.WatchdogStall(), 3*interval))
+	}
+	if wd != nil {
+		log.Printf("Watchdog enabled: stall after %s, dump=%s", cfg.WatchdogStall(), cfg.WatchdogDump)
+		workers.Go("watchdog", func(ctx context.Context) {
+			wd.Run(ctx, time.Second)
+		})
+	}
+	// БД подключается в фоне с повторами: недоступная при старте БД не отключает её навсегда
+	workers.Go("db-manager", func(ctx context.Context) {
+		handler.DB().Run(heartbeat(ctx, "db-manager", db.ReconnectMaxBackoff))
+	})
+	// pgx читает сертификаты при создании пула: обновлённый Secret требует нового пула. Пути
+	// берутся из текущей конфигурации менеджера: hot reload может их поменять или задать впервые.
+	workers.Go("db-tls-watcher", func(ctx context.Context) {
+		ctx = heartbeat(ctx, "db-tls-watcher", base.ReloadInterval())
+		tlsFiles := func() []string { return handler.DB().Config().TLSFiles() }
+		watch.PollPaths(ctx, base.ReloadInterval(), tlsFiles, func() {
+			if err := db.CheckTLSFiles(handler.DB().Config(), time.Now()); err != nil {
+				log.Printf("WARN: Postgres TLS files changed but are invalid: %v", err)
+			}
+			log.Println("Postgres TLS files changed, recreating pool")
+			handler.DB().Reload()
+		})
+	})
+	// очередь вставок останавливается раньше менеджера БД (обратный порядок) и успевает дописать остаток
+	if b := handler.Batcher(); b != nil {
+		log.Printf("POST /db/requests in async mode: batch=%d interval=%s queue=%d", cfg.RequestsBatchSize, cfg.RequestsBatchFlush(), cfg.RequestsQueueSize)
+		workers.Go("requests-batcher", func(ctx context.Context) {
+			b.Run(heartbeat(ctx, "requests-batcher", cfg.RequestsBatchFlush()))
+			log.Printf("Requests queue flushed (pending=%d)", b.Pending())
+		})
+	}
+	workers.Go("health-checks", func(ctx context.Context) {
+		handler.RunHealthChecks(heartbeat(ctx, "health-checks", cfg.HealthInterval()), cfg.HealthInterval())
+	})
+	if len(base.WatchDirs) > 0 {
+		log.Printf("Watching %v for config changes every %s", base.WatchDirs, base.ReloadInterval())
+		workers.Go("config-watcher", func(ctx context.Context) {
+			ctx = heartbeat(ctx, "config-watcher", base.ReloadInterval())
+			watch.Poll(ctx, base.ReloadInterval(), base.WatchDirs, func() {
+				next, err := base.ApplyDirs()
+				if err != nil {
+					log.Printf("WARN: config reload failed, keeping previous values: %v", err)
+					return
+				}
+				handler.ApplyConfig(next)
+				log.Println("Config reloaded from mounted volumes")
+			})
+		})
+	}
+
 	errCh := make(chan error, 1)
 	go func() {
 		log.Println("HTTP server is listening")
@@ -65,9 +138,20 @@ func main() {
 		}
 	}
 
+	// 1. /readyz начинает отвечать 503, но listener ещё принимает запросы: endpoints
+	//    controller и kube-proxy/ingress успевают убрать под из балансировки.
+	handler.SetDraining()
+	log.Printf("Draining: readiness failed, waiting %s before closing listener (in-flight=%d)", cfg.PreDrainDelay(), handler.InFlightCount())
+	time.Sleep(cfg.PreDrainDelay())
+
+	// 2. HTTP-сервер: перестаём принимать соединения и ждём запросы в обработке.
 	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout())
 	defer cancel()
+	log.Printf("Shutting down HTTP server (in-flight=%d)", handler.InFlightCount())
 	if err := srv.Shutdown(shutdownCtx); err != nil {
+		for _, r := range handler.InFlight() {
+			log.Printf("WARN: request still in flight: %s %s for %s", r.Method, r.Path, time.Since(r.Since).Round(time.Millisecond))
+		}
 		log.Printf("Graceful shutdown failed, forcing close: %v", err)
 		if cerr := srv.Close(); cerr != nil {
 			log.Printf("Additional close error: %v", cerr)
@@ -75,8 +159,12 @@ func main() {
 	} else {
 		log.Println("Server stopped gracefully")
 	}
-	if pgClient != nil {
-		pgClient.Close()
+
+	// 3. Фоновые воркеры (в обратном порядке запуска), затем пул БД.
+	workersCtx, cancelWorkers := context.WithTimeout(context.Background(), cfg.ShutdownTimeout())
+	defer cancelWorkers()
+	_ = workers.Shutdown(workersCtx) // незавершённые воркеры уже залогированы
+	if handler.CloseDB() {
 		log.Println("Postgres client closed")
 	}
 }
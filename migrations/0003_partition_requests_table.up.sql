@@ -0,0 +1,38 @@
+-- +migrate Up
+-- requests -> декларативное партиционирование по created_at (месячные партиции requests_YYYY_MM, UTC).
+-- PK партиционированной таблицы обязан включать ключ партиционирования, поэтому он (id, created_at);
+-- id по-прежнему выдаёт последовательность requests_id_seq.
+-- Будущие партиции создаёт и истёкшие удаляет CronJob (db.Client.MaintainPartitions).
+ALTER TABLE requests RENAME TO requests_legacy;
+ALTER TABLE requests_legacy RENAME CONSTRAINT requests_pkey TO requests_legacy_pkey;
+
+CREATE TABLE requests (
+    id BIGINT NOT NULL DEFAULT nextval('requests_id_seq'),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (id, created_at)
+) PARTITION BY RANGE (created_at);
+ALTER SEQUENCE requests_id_seq OWNED BY requests.id;
+
+-- записи вне созданных партиций (например, /db/requests/batch с произвольным createdAt)
+CREATE TABLE requests_default PARTITION OF requests DEFAULT;
+
+-- партиции от самой старой записи до трёх месяцев вперёд
+DO $$
+DECLARE
+    m    date;
+    last date := (date_trunc('month', now() AT TIME ZONE 'UTC') + interval '3 months')::date;
+BEGIN
+    SELECT date_trunc('month', coalesce(min(created_at), now()) AT TIME ZONE 'UTC')::date INTO m FROM requests_legacy;
+    WHILE m <= last LOOP
+        EXECUTE format(
+            'CREATE TABLE %I PARTITION OF requests FOR VALUES FROM (%L) TO (%L)',
+            'requests_' || to_char(m, 'YYYY_MM'),
+            to_char(m, 'YYYY-MM-DD') || ' 00:00:00+00',
+            to_char(m + interval '1 month', 'YYYY-MM-DD') || ' 00:00:00+00'
+        );
+        m := (m + interval '1 month')::date;
+    END LOOP;
+END $$;
+
+INSERT INTO requests (id, created_at) SELECT id, created_at FROM requests_legacy;
+DROP TABLE requests_legacy;
@@ -0,0 +1,15 @@
+-- +migrate Up
+-- pod и путь HTTP-запроса для /stats; у старых записей пустые.
+ALTER TABLE requests
+    ADD COLUMN pod_name TEXT NOT NULL DEFAULT '',
+    ADD COLUMN path TEXT NOT NULL DEFAULT '';
+
+-- Поминутные агрегаты requests, поддерживает CronJob (db.Client.RollupRequests).
+-- /stats читает закрытые минуты отсюда, а хвост после последнего bucket — из requests.
+CREATE TABLE IF NOT EXISTS requests_rollup (
+    bucket   TIMESTAMPTZ NOT NULL,
+    pod_name TEXT NOT NULL,
+    path     TEXT NOT NULL,
+    count    BIGINT NOT NULL,
+    PRIMARY KEY (bucket, pod_name, path)
+);
@@ -17,7 +17,7 @@ if [ -f "${PROJECT_ROOT}/.env" ]; then
     set +a
 else
     echo "[ERROR] Файл .env не найден!"
-    echo "[INFO] Создайте .env на основе .env.example и заполните значения"
+    echo "[INFO] Создайте .env на основе scripts/deploy.env.example и заполните значения"
     exit 1
 fi
 